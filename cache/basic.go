@@ -1,6 +1,9 @@
 package cache
 
-import "sync"
+import (
+	"iter"
+	"sync"
+)
 
 // Basic is a simple cache and has only supports manual eviction.
 type Basic[K comparable, V any] struct {
@@ -55,6 +58,14 @@ func (c *Basic[K, _]) Keys() []K {
 	return out
 }
 
+// Len returns the count of items in the cache.
+func (c *Basic[K, V]) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	return len(c.items)
+}
+
 // Delete deletes the item with provided key from the cache.
 func (c *Basic[K, V]) Delete(key K) {
 	c.Lock()
@@ -69,3 +80,106 @@ func (c *Basic[K, V]) Clear() {
 
 	c.items = make(map[K]V)
 }
+
+// All returns an iterator over the items in the cache, the order is indeterminate.
+// The cache is read locked during iteration, the loop body must not modify the cache.
+func (c *Basic[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.RLock()
+		defer c.RUnlock()
+
+		for k, v := range c.items {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys in the cache, the order is indeterminate.
+// The cache is read locked during iteration, the loop body must not modify the cache.
+func (c *Basic[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.RLock()
+		defer c.RUnlock()
+
+		for k := range c.items {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// GetMany gets the items found for the keys.
+func (c *Basic[K, V]) GetMany(keys []K) map[K]V {
+	c.RLock()
+	defer c.RUnlock()
+
+	out := make(map[K]V, len(keys))
+
+	for _, k := range keys {
+		if v, found := c.items[k]; found {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+// SetMany sets all the items, replacing any existing items.
+func (c *Basic[K, V]) SetMany(items map[K]V) {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, v := range items {
+		c.items[k] = v
+	}
+}
+
+// DeleteFunc deletes all items matching the predicate, returning the count deleted.
+func (c *Basic[K, V]) DeleteFunc(del func(K, V) bool) int {
+	c.Lock()
+	defer c.Unlock()
+
+	count := 0
+
+	for k, v := range c.items {
+		if del(k, v) {
+			delete(c.items, k)
+			count++
+		}
+	}
+
+	return count
+}
+
+// Export returns all items.
+func (c *Basic[K, V]) Export() []Entry[K, V] {
+	c.RLock()
+	defer c.RUnlock()
+
+	out := make([]Entry[K, V], 0, len(c.items))
+	for k, v := range c.items {
+		out = append(out, Entry[K, V]{Key: k, Value: v})
+	}
+
+	return out
+}
+
+// Import adds the entries, replacing any existing items.  Expired entries are skipped, Basic does not support
+// expiry so unexpired entries never expire.
+func (c *Basic[K, V]) Import(entries []Entry[K, V]) {
+	now := nowFunc()
+
+	c.Lock()
+	defer c.Unlock()
+
+	for _, e := range entries {
+		if !e.Expires.IsZero() && !now.Before(e.Expires) {
+			continue
+		}
+
+		c.items[e.Key] = e.Value
+	}
+}
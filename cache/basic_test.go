@@ -50,7 +50,13 @@ func randString(length int) string {
 var _ cache.Cache[string, string] = cache.NewBasic[string, string]()
 
 func TestCache(t *testing.T) {
-	c := cache.NewBasic[string, int]()
+	testCacheContract(t, cache.NewBasic[string, int]())
+}
+
+// testCacheContract validates the Cache contract for an empty cache implementation.
+func testCacheContract(t *testing.T, c cache.Cache[string, int]) {
+	t.Helper()
+
 	// Empty
 	v, ok := c.Get("a")
 	assert.Equal(t, 0, v)
@@ -96,6 +102,12 @@ func TestCache(t *testing.T) {
 	kk = c.Keys()
 	assert.Equal(t, 1, len(kk))
 	assert.Equal(t, []string{"b"}, kk)
+
+	// Clear
+	c.Clear()
+
+	kk = c.Keys()
+	assert.Equal(t, 0, len(kk))
 }
 
 type foo struct {
@@ -1,5 +1,7 @@
 package cache
 
+import "iter"
+
 // Cache is the basic contract for all cache implementations.
 type Cache[K comparable, V any] interface {
 	// Get gets an item from the cache.
@@ -13,3 +15,26 @@ type Cache[K comparable, V any] interface {
 	// Clear resets the cache.
 	Clear()
 }
+
+// Iterable is implemented by caches that support range iteration and bulk operations.
+// Each operation takes the cache lock once.  Iterators hold the read lock for the duration of the loop, the loop body
+// must not modify the cache.
+type Iterable[K comparable, V any] interface {
+	Cache[K, V]
+	// All returns an iterator over the items in the cache.
+	All() iter.Seq2[K, V]
+	// KeysSeq returns an iterator over the keys in the cache.
+	KeysSeq() iter.Seq[K]
+	// GetMany gets the items found for the keys.
+	GetMany(keys []K) map[K]V
+	// SetMany sets all the items, replacing any existing items.
+	SetMany(items map[K]V)
+	// DeleteFunc deletes all items matching the predicate, returning the count deleted.
+	DeleteFunc(del func(K, V) bool) int
+}
+
+// keyValue is an internal pair for tracking items outside the backing store.
+type keyValue[K comparable, V any] struct {
+	key   K
+	value V
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"iter"
 	"sync"
 )
 
@@ -79,3 +80,35 @@ func (c *Exists[K]) Delete(key K) {
 
 	delete(c.items, key)
 }
+
+// KeysSeq returns an iterator over the keys, the order is indeterminate.
+// The exists is read locked during iteration, the loop body must not modify it.
+func (c *Exists[K]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.RLock()
+		defer c.RUnlock()
+
+		for k := range c.items {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// DeleteFunc deletes all keys matching the predicate, returning the count deleted.
+func (c *Exists[K]) DeleteFunc(del func(K) bool) int {
+	c.Lock()
+	defer c.Unlock()
+
+	count := 0
+
+	for k := range c.items {
+		if del(k) {
+			delete(c.items, k)
+			count++
+		}
+	}
+
+	return count
+}
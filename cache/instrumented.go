@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Log field names used by Instrumented.Log.
+const (
+	LogCacheName      = "cache.name"
+	LogCacheHits      = "cache.hits"
+	LogCacheMisses    = "cache.misses"
+	LogCacheHitRatio  = "cache.hit_ratio"
+	LogCacheSets      = "cache.sets"
+	LogCacheDeletes   = "cache.deletes"
+	LogCacheEvictions = "cache.evictions"
+	LogCacheSize      = "cache.size"
+)
+
+// Stats is a point in time snapshot of cache usage.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Sets      uint64
+	Deletes   uint64
+	Evictions uint64
+	Size      int
+}
+
+// HitRatio returns the ratio of hits to lookups, 0 if there are no lookups.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
+
+// Instrumented decorates any Cache with usage statistics.
+// Evictions are reported by the backing cache, register Evicted as its EvictFunc:
+//
+//	lru := cache.NewLRU[string, int](100)
+//	c := cache.NewInstrumented[string, int]("users", lru)
+//	lru.WithEvictFunc(c.Evicted)
+type Instrumented[K comparable, V any] struct {
+	name  string
+	cache Cache[K, V]
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	sets      atomic.Uint64
+	deletes   atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// NewInstrumented wraps the cache `c` with usage statistics, `name` identifies the cache in logs.
+func NewInstrumented[K comparable, V any](name string, c Cache[K, V]) *Instrumented[K, V] {
+	return &Instrumented[K, V]{name: name, cache: c}
+}
+
+// Get gets an item from the cache, recording a hit or miss.
+func (c *Instrumented[K, V]) Get(k K) (V, bool) { //nolint:ireturn // false positive
+	v, found := c.cache.Get(k)
+	if found {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	return v, found
+}
+
+// Set sets any item to the cache, replacing any existing item.
+func (c *Instrumented[K, V]) Set(k K, v V) {
+	c.sets.Add(1)
+	c.cache.Set(k, v)
+}
+
+// Delete deletes the item with provided key from the cache.
+func (c *Instrumented[K, V]) Delete(key K) {
+	c.deletes.Add(1)
+	c.cache.Delete(key)
+}
+
+// Keys returns existing keys, the order is indeterminate.
+func (c *Instrumented[K, V]) Keys() []K {
+	return c.cache.Keys()
+}
+
+// Clear resets the cache.  Statistics are not reset.
+func (c *Instrumented[K, V]) Clear() {
+	c.cache.Clear()
+}
+
+// All returns an iterator over the items in the backing cache.  Iteration is not recorded in the statistics.
+// If the backing cache is not Iterable, items are read key by key and may race with concurrent deletes.
+func (c *Instrumented[K, V]) All() iter.Seq2[K, V] {
+	if i, ok := c.cache.(Iterable[K, V]); ok {
+		return i.All()
+	}
+
+	return func(yield func(K, V) bool) {
+		for _, k := range c.cache.Keys() {
+			v, found := c.cache.Get(k)
+			if found && !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys in the backing cache.
+func (c *Instrumented[K, V]) KeysSeq() iter.Seq[K] {
+	if i, ok := c.cache.(Iterable[K, V]); ok {
+		return i.KeysSeq()
+	}
+
+	return slices.Values(c.cache.Keys())
+}
+
+// GetMany gets the items found for the keys, recording a hit or miss per key.
+func (c *Instrumented[K, V]) GetMany(keys []K) map[K]V {
+	var out map[K]V
+
+	if i, ok := c.cache.(Iterable[K, V]); ok {
+		out = i.GetMany(keys)
+	} else {
+		out = make(map[K]V, len(keys))
+
+		for _, k := range keys {
+			if v, found := c.cache.Get(k); found {
+				out[k] = v
+			}
+		}
+	}
+
+	c.hits.Add(uint64(len(out)))
+	c.misses.Add(uint64(len(keys) - len(out)))
+
+	return out
+}
+
+// SetMany sets all the items, replacing any existing items.
+func (c *Instrumented[K, V]) SetMany(items map[K]V) {
+	c.sets.Add(uint64(len(items)))
+
+	if i, ok := c.cache.(Iterable[K, V]); ok {
+		i.SetMany(items)
+
+		return
+	}
+
+	for k, v := range items {
+		c.cache.Set(k, v)
+	}
+}
+
+// DeleteFunc deletes all items matching the predicate, returning the count deleted.
+func (c *Instrumented[K, V]) DeleteFunc(del func(K, V) bool) int {
+	var count int
+
+	if i, ok := c.cache.(Iterable[K, V]); ok {
+		count = i.DeleteFunc(del)
+	} else {
+		for k, v := range c.All() {
+			if del(k, v) {
+				c.cache.Delete(k)
+				count++
+			}
+		}
+	}
+
+	c.deletes.Add(uint64(count))
+
+	return count
+}
+
+// Evicted records an eviction, it matches EvictFunc to be registered with the backing cache.
+func (c *Instrumented[K, V]) Evicted(_ K, _ V) {
+	c.evictions.Add(1)
+}
+
+// Len returns the count of items in the backing cache.
+func (c *Instrumented[K, V]) Len() int {
+	if l, ok := c.cache.(interface{ Len() int }); ok {
+		return l.Len()
+	}
+
+	return len(c.cache.Keys())
+}
+
+// Stats returns a snapshot of the cache statistics.
+func (c *Instrumented[K, V]) Stats() Stats {
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Sets:      c.sets.Load(),
+		Deletes:   c.deletes.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      c.Len(),
+	}
+}
+
+// Log logs a summary of the cache statistics.
+func (c *Instrumented[K, V]) Log(l zerolog.Logger) {
+	s := c.Stats()
+
+	l.Info().
+		Str(LogCacheName, c.name).
+		Uint64(LogCacheHits, s.Hits).
+		Uint64(LogCacheMisses, s.Misses).
+		Float64(LogCacheHitRatio, s.HitRatio()).
+		Uint64(LogCacheSets, s.Sets).
+		Uint64(LogCacheDeletes, s.Deletes).
+		Uint64(LogCacheEvictions, s.Evictions).
+		Int(LogCacheSize, s.Size).
+		Msg("cache stats")
+}
+
+// LogEvery logs a summary of the cache statistics every `interval` until the ctx is done.
+// This blocks, it is intended to be run in a separate go routine.
+func (c *Instrumented[K, V]) LogEvery(ctx context.Context, l zerolog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Log(l)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bir/iken/cache"
+)
+
+// Type assertion
+var _ cache.Cache[string, string] = cache.NewInstrumented("test", cache.NewNoOp[string, string]())
+
+func TestInstrumentedContract(t *testing.T) {
+	testCacheContract(t, cache.NewInstrumented("test", cache.NewBasic[string, int]()))
+}
+
+func TestInstrumented(t *testing.T) {
+	lru := cache.NewLRU[string, int](2)
+	c := cache.NewInstrumented[string, int]("test", lru)
+	lru.WithEvictFunc(c.Evicted)
+
+	assert.Equal(t, cache.Stats{}, c.Stats())
+	assert.Equal(t, float64(0), c.Stats().HitRatio())
+
+	c.Get("a")
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Delete("b")
+
+	assert.Equal(t, cache.Stats{
+		Hits:      2,
+		Misses:    1,
+		Sets:      3,
+		Deletes:   1,
+		Evictions: 1,
+		Size:      1,
+	}, c.Stats())
+	assert.InDelta(t, 0.666, c.Stats().HitRatio(), 0.001)
+	assert.Equal(t, []string{"c"}, c.Keys())
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestInstrumentedTTLEvictions(t *testing.T) {
+	ttl := cache.NewTTL[string, int](time.Nanosecond, 0)
+	c := cache.NewInstrumented[string, int]("test", ttl)
+	ttl.WithEvictFunc(c.Evicted)
+
+	c.Set("a", 1)
+	c.Set("b", 1)
+	time.Sleep(time.Millisecond)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+
+	ttl.DeleteExpired()
+
+	assert.Equal(t, uint64(2), c.Stats().Evictions)
+	assert.Equal(t, 0, c.Stats().Size)
+}
+
+func TestInstrumentedNoLen(t *testing.T) {
+	c := cache.NewInstrumented("test", cache.NewNoOp[string, int]())
+
+	c.Set("a", 1)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestInstrumentedLog(t *testing.T) {
+	logBuffer := bytes.NewBuffer(nil)
+	c := cache.NewInstrumented("test", cache.NewBasic[string, int]())
+
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("b")
+	c.Log(zerolog.New(logBuffer))
+
+	assert.Equal(t, `{"level":"info","cache.name":"test","cache.hits":1,"cache.misses":1,"cache.hit_ratio":0.5,`+
+		`"cache.sets":1,"cache.deletes":0,"cache.evictions":0,"cache.size":1,"message":"cache stats"}
+`, logBuffer.String())
+}
+
+type syncBuffer struct {
+	ch chan string
+}
+
+func (b syncBuffer) Write(p []byte) (int, error) {
+	b.ch <- string(p)
+
+	return len(p), nil
+}
+
+func TestInstrumentedLogEvery(t *testing.T) {
+	out := syncBuffer{ch: make(chan string, 10)}
+	c := cache.NewInstrumented("test", cache.NewBasic[string, int]())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		c.LogEvery(ctx, zerolog.New(out), time.Millisecond)
+	}()
+
+	line := <-out.ch
+	assert.True(t, strings.Contains(line, `"cache.name":"test"`), line)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,160 @@
+package cache_test
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bir/iken/cache"
+)
+
+// Type assertions
+var (
+	_ cache.Iterable[string, string] = cache.NewBasic[string, string]()
+	_ cache.Iterable[string, string] = cache.NewNoOp[string, string]()
+	_ cache.Iterable[string, string] = cache.NewTTL[string, string](0, 0)
+	_ cache.Iterable[string, string] = cache.NewLRU[string, string](1)
+	_ cache.Iterable[string, string] = cache.NewSharded[string, string](1)
+	_ cache.Iterable[string, string] = cache.NewInstrumented("test", cache.NewBasic[string, string]())
+)
+
+// cacheOnly hides the Iterable implementation of the wrapped cache.
+type cacheOnly struct {
+	cache.Cache[string, int]
+}
+
+func TestIterable(t *testing.T) {
+	tests := []struct {
+		name string
+		c    cache.Iterable[string, int]
+	}{
+		{"basic", cache.NewBasic[string, int]()},
+		{"ttl", cache.NewTTL[string, int](0, 0)},
+		{"lru", cache.NewLRU[string, int](10)},
+		{"sharded", cache.NewSharded[string, int](4)},
+		{"instrumented", cache.NewInstrumented[string, int]("test", cache.NewBasic[string, int]())},
+		{"instrumented fallback", cache.NewInstrumented[string, int]("test", cacheOnly{cache.NewBasic[string, int]()})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testIterableContract(t, tt.c)
+		})
+	}
+}
+
+// testIterableContract validates the Iterable contract for an empty cache implementation.
+func testIterableContract(t *testing.T, c cache.Iterable[string, int]) {
+	t.Helper()
+
+	assert.Empty(t, maps.Collect(c.All()))
+	assert.Empty(t, slices.Collect(c.KeysSeq()))
+	assert.Empty(t, c.GetMany([]string{"a"}))
+
+	c.SetMany(map[string]int{"a": 1, "b": 2, "c": 3, "d": 4})
+
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 3, "d": 4}, maps.Collect(c.All()))
+
+	kk := slices.Collect(c.KeysSeq())
+	sort.Strings(kk)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, kk)
+
+	assert.Equal(t, map[string]int{"a": 1, "c": 3}, c.GetMany([]string{"a", "c", "missing"}))
+
+	// Early termination
+	count := 0
+	for range c.All() {
+		count++
+
+		break
+	}
+
+	assert.Equal(t, 1, count)
+
+	count = 0
+	for range c.KeysSeq() {
+		count++
+
+		break
+	}
+
+	assert.Equal(t, 1, count)
+
+	deleted := c.DeleteFunc(func(_ string, v int) bool {
+		return v%2 == 0
+	})
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, map[string]int{"a": 1, "c": 3}, maps.Collect(c.All()))
+
+	// Overwrite
+	c.SetMany(map[string]int{"a": 10})
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 10, v)
+}
+
+func TestNoOpIterable(t *testing.T) {
+	c := cache.NewNoOp[string, int]()
+
+	c.SetMany(map[string]int{"a": 1})
+	assert.Empty(t, maps.Collect(c.All()))
+	assert.Empty(t, slices.Collect(c.KeysSeq()))
+	assert.Empty(t, c.GetMany([]string{"a"}))
+	assert.Equal(t, 0, c.DeleteFunc(func(string, int) bool { return true }))
+}
+
+func TestLRUIterableOrder(t *testing.T) {
+	evicted := 0
+	c := cache.NewLRU[int, int](2).WithEvictFunc(func(int, int) { evicted++ })
+
+	c.Set(1, 1)
+	c.Set(2, 2)
+	c.Set(3, 3)
+	assert.Equal(t, 1, evicted)
+
+	c.GetMany([]int{2})
+	assert.Equal(t, []int{2, 3}, slices.Collect(c.KeysSeq()))
+
+	c.SetMany(map[int]int{3: 30})
+	assert.Equal(t, []int{3, 2}, slices.Collect(c.KeysSeq()))
+
+	c.SetMany(map[int]int{4: 4})
+	assert.Equal(t, []int{4, 3}, slices.Collect(c.KeysSeq()))
+	assert.Equal(t, 2, evicted)
+}
+
+func TestInstrumentedIterableStats(t *testing.T) {
+	c := cache.NewInstrumented[string, int]("test", cache.NewBasic[string, int]())
+
+	c.SetMany(map[string]int{"a": 1, "b": 2})
+	c.GetMany([]string{"a", "b", "c"})
+	c.DeleteFunc(func(k string, _ int) bool { return k == "a" })
+
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 1, Sets: 2, Deletes: 1, Size: 1}, c.Stats())
+}
+
+func TestExistsIterable(t *testing.T) {
+	c := cache.NewExists[int]()
+
+	for i := range 10 {
+		c.Mark(i)
+	}
+
+	kk := slices.Collect(c.KeysSeq())
+	sort.Ints(kk)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, kk)
+
+	for range c.KeysSeq() {
+		break
+	}
+
+	assert.Equal(t, 5, c.DeleteFunc(func(k int) bool { return k%2 == 1 }))
+
+	kk = slices.Collect(c.KeysSeq())
+	sort.Ints(kk)
+	assert.Equal(t, []int{0, 2, 4, 6, 8}, kk)
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Loader loads the value for a key on a cache miss.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// Loaded is the entry stored by Loading in the backing cache.  Err is set for negative (failed) entries.
+type Loaded[V any] struct {
+	Value    V
+	Err      error
+	LoadedAt time.Time
+}
+
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// invalidated is set, guarded by Loading.mu, when the key is deleted or cleared during the load.  The result is
+	// still returned to waiters but not stored.
+	invalidated bool
+}
+
+// ErrLoaderPanic is returned when the Loader panics.
+var ErrLoaderPanic = errors.New("loader panic")
+
+// Loading is a read-through cache.  Misses are loaded via the Loader, with concurrent misses for the same key
+// collapsed into a single load.  Expiry and eviction are delegated to the backing cache, e.g. TTL or LRU.
+//
+// Loads are shared, so they run with a ctx that is not cancelled when a caller gives up.  Callers stop waiting when
+// their own ctx is done.
+type Loading[K comparable, V any] struct {
+	cache        Cache[K, Loaded[V]]
+	loader       Loader[K, V]
+	refreshAfter time.Duration
+	errorTTL     time.Duration
+
+	mu    sync.Mutex
+	calls map[K]*loadCall[V]
+	loads sync.WaitGroup
+}
+
+// NewLoading creates a read-through cache backed by `c`, loading misses with `loader`.
+func NewLoading[K comparable, V any](c Cache[K, Loaded[V]], loader Loader[K, V]) *Loading[K, V] {
+	return &Loading[K, V]{
+		cache:  c,
+		loader: loader,
+		calls:  make(map[K]*loadCall[V]),
+	}
+}
+
+// WithRefreshAfter enables refresh-ahead.  Entries older than `d` are reloaded in the background while the stale
+// value continues to be served.  Set `d` shorter than the expiry of the backing cache.
+func (l *Loading[K, V]) WithRefreshAfter(d time.Duration) *Loading[K, V] {
+	l.refreshAfter = d
+
+	return l
+}
+
+// WithErrorTTL enables negative caching.  Loader errors are cached and returned for `d` before loading again.
+// Context cancellation errors are never cached.
+func (l *Loading[K, V]) WithErrorTTL(d time.Duration) *Loading[K, V] {
+	l.errorTTL = d
+
+	return l
+}
+
+// Get returns the cached value for the key, loading it on a miss.
+func (l *Loading[K, V]) Get(ctx context.Context, k K) (V, error) { //nolint:ireturn // false positive
+	entry, found := l.cache.Get(k)
+	if found {
+		age := nowFunc().Sub(entry.LoadedAt)
+
+		if entry.Err != nil {
+			if age < l.errorTTL {
+				return entry.Value, entry.Err
+			}
+		} else {
+			if l.refreshAfter > 0 && age >= l.refreshAfter {
+				l.start(ctx, k, true)
+			}
+
+			return entry.Value, nil
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		var zero V
+
+		return zero, err //nolint:wrapcheck // ctx error
+	}
+
+	call := l.start(ctx, k, false)
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+
+		return zero, ctx.Err()
+	}
+}
+
+// Delete deletes the item with provided key from the cache.  An in flight load of the key is not stored, later Gets
+// load again.
+func (l *Loading[K, V]) Delete(k K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if call, found := l.calls[k]; found {
+		call.invalidated = true
+		delete(l.calls, k)
+	}
+
+	l.cache.Delete(k)
+}
+
+// Clear resets the cache.  In flight loads are not stored, see Delete.
+func (l *Loading[K, V]) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for k, call := range l.calls {
+		call.invalidated = true
+		delete(l.calls, k)
+	}
+
+	l.cache.Clear()
+}
+
+// Wait blocks until all in flight loads, including background refreshes, are done.
+func (l *Loading[K, V]) Wait() {
+	l.loads.Wait()
+}
+
+// start returns the in flight load of the key, or starts one.
+func (l *Loading[K, V]) start(ctx context.Context, k K, refresh bool) *loadCall[V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if call, found := l.calls[k]; found {
+		return call
+	}
+
+	call := &loadCall[V]{done: make(chan struct{})}
+	l.calls[k] = call
+	l.loads.Add(1)
+
+	go l.load(context.WithoutCancel(ctx), k, call, refresh)
+
+	return call
+}
+
+// load calls the loader and stores the result.  Failed background refreshes leave the existing entry in place.
+func (l *Loading[K, V]) load(ctx context.Context, k K, call *loadCall[V], refresh bool) {
+	defer l.loads.Done()
+
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("%w: %v", ErrLoaderPanic, r)
+		}
+
+		l.mu.Lock()
+		if l.calls[k] == call {
+			delete(l.calls, k)
+		}
+		l.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	call.value, call.err = l.loader(ctx, k)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if call.invalidated {
+		return
+	}
+
+	switch {
+	case call.err == nil:
+		l.cache.Set(k, Loaded[V]{Value: call.value, LoadedAt: nowFunc()})
+	case !refresh && l.errorTTL > 0 && !isContextErr(call.err):
+		l.cache.Set(k, Loaded[V]{Value: call.value, Err: call.err, LoadedAt: nowFunc()})
+	}
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,354 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errLoad = errors.New("load failed")
+
+func TestLoadingSingleFlight(t *testing.T) {
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+	l := NewLoading(NewBasic[string, Loaded[int]](), func(_ context.Context, k string) (int, error) {
+		calls.Add(1)
+		<-release
+
+		return len(k), nil
+	})
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			v, err := l.Get(context.Background(), "abc")
+			assert.NoError(t, err)
+			assert.Equal(t, 3, v)
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		return len(l.calls) == 1
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Cached
+	v, err := l.Get(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Delete forces reload
+	l.Delete("abc")
+
+	_, err = l.Get(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+
+	l.Clear()
+
+	_, err = l.Get(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestLoadingRefreshAhead(t *testing.T) {
+	now := testClock(t)
+
+	var calls atomic.Int32
+
+	l := NewLoading(NewBasic[string, Loaded[int32]](), func(_ context.Context, _ string) (int32, error) {
+		return calls.Add(1), nil
+	}).WithRefreshAfter(time.Minute)
+
+	v, err := l.Get(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	now.Add(int64(time.Minute))
+
+	// Stale value served while refreshing.
+	v, err = l.Get(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	l.Wait()
+
+	v, err = l.Get(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), v)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLoadingRefreshErrorKeepsValue(t *testing.T) {
+	now := testClock(t)
+
+	var fail atomic.Bool
+
+	l := NewLoading(NewBasic[string, Loaded[int]](), func(_ context.Context, _ string) (int, error) {
+		if fail.Load() {
+			return 0, errLoad
+		}
+
+		return 1, nil
+	}).WithRefreshAfter(time.Minute).WithErrorTTL(time.Minute)
+
+	_, err := l.Get(context.Background(), "a")
+	assert.NoError(t, err)
+
+	fail.Store(true)
+	now.Add(int64(time.Minute))
+
+	for range 10 {
+		v, err := l.Get(context.Background(), "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}
+
+	l.Wait()
+}
+
+func TestLoadingNegative(t *testing.T) {
+	now := testClock(t)
+
+	var calls atomic.Int32
+
+	l := NewLoading(NewBasic[string, Loaded[int]](), func(_ context.Context, _ string) (int, error) {
+		calls.Add(1)
+
+		return 0, errLoad
+	})
+
+	// Not cached by default.
+	_, err := l.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, errLoad)
+	_, err = l.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, errLoad)
+	assert.Equal(t, int32(2), calls.Load())
+
+	l.WithErrorTTL(time.Second)
+
+	_, err = l.Get(context.Background(), "b")
+	assert.ErrorIs(t, err, errLoad)
+	_, err = l.Get(context.Background(), "b")
+	assert.ErrorIs(t, err, errLoad)
+	assert.Equal(t, int32(3), calls.Load())
+
+	now.Add(int64(time.Second))
+
+	_, err = l.Get(context.Background(), "b")
+	assert.ErrorIs(t, err, errLoad)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestLoadingContextErrorsNotCached(t *testing.T) {
+	c := NewBasic[string, Loaded[int]]()
+	l := NewLoading(c, func(_ context.Context, _ string) (int, error) {
+		return 0, context.DeadlineExceeded
+	}).WithErrorTTL(time.Minute)
+
+	_, err := l.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, c.Keys())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = l.Get(ctx, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, c.Keys())
+}
+
+func TestLoadingWaiterCancel(t *testing.T) {
+	release := make(chan struct{})
+	l := NewLoading(NewBasic[string, Loaded[int]](), func(_ context.Context, _ string) (int, error) {
+		<-release
+
+		return 1, nil
+	})
+
+	leader := make(chan struct{})
+
+	go func() {
+		defer close(leader)
+
+		_, _ = l.Get(context.Background(), "a")
+	}()
+
+	assert.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		return len(l.calls) == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := l.Get(ctx, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	<-leader
+}
+
+func TestLoadingLeaderCancel(t *testing.T) {
+	release := make(chan struct{})
+	l := NewLoading(NewBasic[string, Loaded[int]](), func(ctx context.Context, _ string) (int, error) {
+		<-release
+
+		return 1, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+
+	go func() {
+		_, err := l.Get(ctx, "a")
+		leader <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		return len(l.calls) == 1
+	}, time.Second, time.Millisecond)
+
+	waiter := make(chan int)
+
+	go func() {
+		v, err := l.Get(context.Background(), "a")
+		assert.NoError(t, err)
+		waiter <- v
+	}()
+
+	cancel()
+	assert.ErrorIs(t, <-leader, context.Canceled)
+
+	close(release)
+	assert.Equal(t, 1, <-waiter, "the load is not cancelled with the leader")
+}
+
+func TestLoadingPanic(t *testing.T) {
+	var calls atomic.Int32
+
+	l := NewLoading(NewBasic[string, Loaded[int]](), func(_ context.Context, _ string) (int, error) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+
+		return 1, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := l.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrLoaderPanic)
+
+	v, err := l.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestLoadingRefreshOnce(t *testing.T) {
+	now := testClock(t)
+
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+	l := NewLoading(NewBasic[string, Loaded[int32]](), func(_ context.Context, _ string) (int32, error) {
+		n := calls.Add(1)
+		if n > 1 {
+			<-release
+		}
+
+		return n, nil
+	}).WithRefreshAfter(time.Minute)
+
+	_, err := l.Get(context.Background(), "a")
+	assert.NoError(t, err)
+
+	now.Add(int64(time.Minute))
+
+	for range 10 {
+		v, err := l.Get(context.Background(), "a")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), v)
+	}
+
+	close(release)
+	l.Wait()
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLoadingDeleteDuringLoad(t *testing.T) {
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+	c := NewBasic[string, Loaded[int32]]()
+	l := NewLoading(c, func(_ context.Context, _ string) (int32, error) {
+		n := calls.Add(1)
+		if n == 1 {
+			<-release
+		}
+
+		return n, nil
+	})
+
+	leader := make(chan int32)
+
+	go func() {
+		v, err := l.Get(context.Background(), "a")
+		assert.NoError(t, err)
+		leader <- v
+	}()
+
+	assert.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		return len(l.calls) == 1
+	}, time.Second, time.Millisecond)
+
+	l.Delete("a")
+
+	// A Get after the Delete does not join the invalidated load.
+	v, err := l.Get(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), v)
+
+	close(release)
+	assert.Equal(t, int32(1), <-leader, "waiters still receive the result")
+	l.Wait()
+
+	entry, found := c.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, int32(2), entry.Value, "the invalidated load is not stored")
+
+	l.Clear()
+
+	v, err = l.Get(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), v)
+}
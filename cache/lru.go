@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"container/list"
+	"iter"
+	"sync"
+)
+
+// EvictFunc is called with the key and value of items evicted by a cache.
+type EvictFunc[K comparable, V any] func(K, V)
+
+// LRU is a thread safe cache bounded by capacity.  When full, the least recently used item is evicted.
+// Get and Set are O(1).
+type LRU[K comparable, V any] struct {
+	*sync.Mutex
+
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List // Front is the most recently used.
+	onEvict  EvictFunc[K, V]
+}
+
+// NewLRU creates a new thread safe cache holding at most `capacity` items.  A capacity < 1 is treated as 1.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &LRU[K, V]{
+		Mutex:    &sync.Mutex{},
+		capacity: capacity,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// WithEvictFunc registers a callback invoked when items are evicted to make room for new items.  It is not invoked
+// for Delete or Clear.  The callback is invoked while the cache is locked, it must not call back into the cache.
+func (c *LRU[K, V]) WithEvictFunc(fn EvictFunc[K, V]) *LRU[K, V] {
+	c.onEvict = fn
+
+	return c
+}
+
+// Set sets any item to the cache, replacing any existing item, and marks it as most recently used.
+func (c *LRU[K, V]) Set(k K, v V) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, found := c.items[k]; found {
+		e.Value.(*keyValue[K, V]).value = v //nolint:forcetypeassert // internal invariant
+		c.order.MoveToFront(e)
+
+		return
+	}
+
+	c.items[k] = c.order.PushFront(&keyValue[K, V]{key: k, value: v})
+
+	if c.order.Len() > c.capacity {
+		c.removeOldest()
+	}
+}
+
+// Get gets an item from the cache and marks it as most recently used.
+// Returns the item or zero value, and a bool indicating whether the key was found.
+func (c *LRU[K, V]) Get(k K) (V, bool) { //nolint:ireturn // false positive
+	c.Lock()
+	defer c.Unlock()
+
+	e, found := c.items[k]
+	if !found {
+		var zero V
+
+		return zero, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*keyValue[K, V]).value, true //nolint:forcetypeassert // internal invariant
+}
+
+// Peek gets an item from the cache without updating its recent usage.
+func (c *LRU[K, V]) Peek(k K) (V, bool) { //nolint:ireturn // false positive
+	c.Lock()
+	defer c.Unlock()
+
+	e, found := c.items[k]
+	if !found {
+		var zero V
+
+		return zero, false
+	}
+
+	return e.Value.(*keyValue[K, V]).value, true //nolint:forcetypeassert // internal invariant
+}
+
+// Keys returns existing keys, ordered from most to least recently used.
+func (c *LRU[K, V]) Keys() []K {
+	c.Lock()
+	defer c.Unlock()
+
+	l := c.order.Len()
+	if l == 0 {
+		return nil
+	}
+
+	out := make([]K, 0, l)
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		out = append(out, e.Value.(*keyValue[K, V]).key) //nolint:forcetypeassert // internal invariant
+	}
+
+	return out
+}
+
+// Len returns the count of items in the cache.
+func (c *LRU[K, V]) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.order.Len()
+}
+
+// Delete deletes the item with provided key from the cache.
+func (c *LRU[K, V]) Delete(key K) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, found := c.items[key]; found {
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// Clear resets the cache.
+func (c *LRU[K, V]) Clear() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.items = make(map[K]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+func (c *LRU[K, V]) removeOldest() {
+	e := c.order.Back()
+	if e == nil {
+		return
+	}
+
+	entry := c.order.Remove(e).(*keyValue[K, V]) //nolint:forcetypeassert // internal invariant
+	delete(c.items, entry.key)
+
+	if c.onEvict != nil {
+		c.onEvict(entry.key, entry.value)
+	}
+}
+
+// All returns an iterator over the items, ordered from most to least recently used.  Usage is not updated.
+// The cache is locked during iteration, the loop body must not access the cache.
+func (c *LRU[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.Lock()
+		defer c.Unlock()
+
+		for e := c.order.Front(); e != nil; e = e.Next() {
+			entry := e.Value.(*keyValue[K, V]) //nolint:forcetypeassert // internal invariant
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys, ordered from most to least recently used.  Usage is not updated.
+// The cache is locked during iteration, the loop body must not access the cache.
+func (c *LRU[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range c.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// GetMany gets the items found for the keys, marking each as most recently used.
+func (c *LRU[K, V]) GetMany(keys []K) map[K]V {
+	c.Lock()
+	defer c.Unlock()
+
+	out := make(map[K]V, len(keys))
+
+	for _, k := range keys {
+		if e, found := c.items[k]; found {
+			c.order.MoveToFront(e)
+			out[k] = e.Value.(*keyValue[K, V]).value //nolint:forcetypeassert // internal invariant
+		}
+	}
+
+	return out
+}
+
+// SetMany sets all the items, replacing any existing items.  Items beyond capacity are evicted as with Set.
+func (c *LRU[K, V]) SetMany(items map[K]V) {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, v := range items {
+		if e, found := c.items[k]; found {
+			e.Value.(*keyValue[K, V]).value = v //nolint:forcetypeassert // internal invariant
+			c.order.MoveToFront(e)
+
+			continue
+		}
+
+		c.items[k] = c.order.PushFront(&keyValue[K, V]{key: k, value: v})
+
+		if c.order.Len() > c.capacity {
+			c.removeOldest()
+		}
+	}
+}
+
+// DeleteFunc deletes all items matching the predicate, returning the count deleted.
+func (c *LRU[K, V]) DeleteFunc(del func(K, V) bool) int {
+	c.Lock()
+	defer c.Unlock()
+
+	count := 0
+
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+
+		entry := e.Value.(*keyValue[K, V]) //nolint:forcetypeassert // internal invariant
+		if del(entry.key, entry.value) {
+			c.order.Remove(e)
+			delete(c.items, entry.key)
+			count++
+		}
+
+		e = next
+	}
+
+	return count
+}
+
+// Export returns all items, ordered from least to most recently used so Import restores the usage order.
+func (c *LRU[K, V]) Export() []Entry[K, V] {
+	c.Lock()
+	defer c.Unlock()
+
+	out := make([]Entry[K, V], 0, c.order.Len())
+	for e := c.order.Back(); e != nil; e = e.Prev() {
+		entry := e.Value.(*keyValue[K, V]) //nolint:forcetypeassert // internal invariant
+		out = append(out, Entry[K, V]{Key: entry.key, Value: entry.value})
+	}
+
+	return out
+}
+
+// Import sets the entries in order, replacing any existing items.  Expired entries are skipped.  LRU does not
+// support expiry so unexpired entries never expire.
+func (c *LRU[K, V]) Import(entries []Entry[K, V]) {
+	now := nowFunc()
+
+	for _, e := range entries {
+		if e.Expires.IsZero() || now.Before(e.Expires) {
+			c.Set(e.Key, e.Value)
+		}
+	}
+}
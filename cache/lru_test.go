@@ -0,0 +1,106 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bir/iken/cache"
+)
+
+// Type assertion
+var _ cache.Cache[string, string] = cache.NewLRU[string, string](1)
+
+func ExampleLRU() {
+	c := cache.NewLRU[string, int](2).WithEvictFunc(func(k string, v int) {
+		fmt.Println("evicted", k, v)
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+	fmt.Println(c.Keys())
+
+	// Output:
+	// evicted b 2
+	// [c a]
+}
+
+func TestLRUContract(t *testing.T) {
+	testCacheContract(t, cache.NewLRU[string, int](10))
+}
+
+func TestLRU(t *testing.T) {
+	var evicted []string
+
+	c := cache.NewLRU[string, int](3).WithEvictFunc(func(k string, _ int) {
+		evicted = append(evicted, k)
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	assert.Equal(t, []string{"c", "b", "a"}, c.Keys())
+	assert.Equal(t, 3, c.Len())
+
+	// Peek does not update usage.
+	v, ok := c.Peek("a")
+	assert.Equal(t, 1, v)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"c", "b", "a"}, c.Keys())
+
+	// Get updates usage.
+	v, ok = c.Get("a")
+	assert.Equal(t, 1, v)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "c", "b"}, c.Keys())
+
+	// Set existing updates value and usage without eviction.
+	c.Set("b", 20)
+	assert.Equal(t, []string{"b", "a", "c"}, c.Keys())
+	assert.Empty(t, evicted)
+
+	// New item evicts least recently used.
+	c.Set("d", 4)
+	assert.Equal(t, []string{"d", "b", "a"}, c.Keys())
+	assert.Equal(t, []string{"c"}, evicted)
+
+	_, ok = c.Get("c")
+	assert.False(t, ok)
+
+	_, ok = c.Peek("c")
+	assert.False(t, ok)
+
+	// Delete does not call the evict func.
+	c.Delete("d")
+	c.Delete("missing")
+	assert.Equal(t, []string{"b", "a"}, c.Keys())
+	assert.Equal(t, []string{"c"}, evicted)
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+	assert.Nil(t, c.Keys())
+}
+
+func TestLRUMinCapacity(t *testing.T) {
+	c := cache.NewLRU[int, int](0)
+
+	c.Set(1, 1)
+	c.Set(2, 2)
+	assert.Equal(t, []int{2}, c.Keys())
+}
+
+func BenchmarkLRU(b *testing.B) {
+	c := cache.NewLRU[int, int](1000)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Set(i%2000, i)
+			c.Get(i % 1500)
+			i++
+		}
+	})
+}
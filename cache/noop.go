@@ -1,5 +1,7 @@
 package cache
 
+import "iter"
+
 // NoOp is a facade cache, it never returns a hit.
 // Useful for easily disabling cache at runtime.
 type NoOp[K comparable, V any] struct{}
@@ -30,3 +32,27 @@ func (c *NoOp[K, V]) Delete(_ K) {
 // Clear no-op.
 func (c *NoOp[K, V]) Clear() {
 }
+
+// All always returns an empty iterator.
+func (c *NoOp[K, V]) All() iter.Seq2[K, V] {
+	return func(func(K, V) bool) {}
+}
+
+// KeysSeq always returns an empty iterator.
+func (c *NoOp[K, V]) KeysSeq() iter.Seq[K] {
+	return func(func(K) bool) {}
+}
+
+// GetMany always returns an empty map.
+func (c *NoOp[K, V]) GetMany(_ []K) map[K]V {
+	return map[K]V{}
+}
+
+// SetMany no-op.
+func (c *NoOp[K, V]) SetMany(_ map[K]V) {
+}
+
+// DeleteFunc always returns 0.
+func (c *NoOp[K, V]) DeleteFunc(_ func(K, V) bool) int {
+	return 0
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"hash/maphash"
+	"iter"
+	"maps"
+)
+
+// Sharded is a thread safe cache that spreads keys across independently locked shards, reducing lock contention
+// under heavy concurrent writes.  Keys are assigned to shards with a maphash of the key.
+type Sharded[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []*Basic[K, V]
+}
+
+// NewSharded creates a new thread safe cache with `shardCount` shards.  A shardCount < 1 is treated as 1.
+func NewSharded[K comparable, V any](shardCount int) *Sharded[K, V] {
+	if shardCount < 1 {
+		shardCount = 1
+	}
+
+	shards := make([]*Basic[K, V], shardCount)
+	for i := range shards {
+		shards[i] = NewBasic[K, V]()
+	}
+
+	return &Sharded[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: shards,
+	}
+}
+
+func (c *Sharded[K, V]) index(k K) uint64 {
+	return maphash.Comparable(c.seed, k) % uint64(len(c.shards))
+}
+
+func (c *Sharded[K, V]) shard(k K) *Basic[K, V] {
+	return c.shards[c.index(k)]
+}
+
+// Set sets any item to the cache, replacing any existing item.
+func (c *Sharded[K, V]) Set(k K, v V) {
+	c.shard(k).Set(k, v)
+}
+
+// Get gets an item from the cache.
+// Returns the item or zero value, and a bool indicating whether the key was found.
+func (c *Sharded[K, V]) Get(k K) (V, bool) { //nolint:ireturn // false positive
+	return c.shard(k).Get(k)
+}
+
+// Keys returns existing keys, the order is indeterminate.
+func (c *Sharded[K, V]) Keys() []K {
+	var out []K
+
+	for _, s := range c.shards {
+		out = append(out, s.Keys()...)
+	}
+
+	return out
+}
+
+// Len returns the count of items in the cache.
+func (c *Sharded[K, V]) Len() int {
+	l := 0
+
+	for _, s := range c.shards {
+		l += s.Len()
+	}
+
+	return l
+}
+
+// Delete deletes the item with provided key from the cache.
+func (c *Sharded[K, V]) Delete(key K) {
+	c.shard(key).Delete(key)
+}
+
+// Clear resets the cache.  Shards are cleared independently, this is not atomic across shards.
+func (c *Sharded[K, V]) Clear() {
+	for _, s := range c.shards {
+		s.Clear()
+	}
+}
+
+// All returns an iterator over the items in the cache, the order is indeterminate.
+// Each shard is read locked while it is iterated, the loop body must not modify the cache.
+func (c *Sharded[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, s := range c.shards {
+			for k, v := range s.All() {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys in the cache, the order is indeterminate.
+// Each shard is read locked while it is iterated, the loop body must not modify the cache.
+func (c *Sharded[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for _, s := range c.shards {
+			for k := range s.KeysSeq() {
+				if !yield(k) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetMany gets the items found for the keys.  Keys are grouped so each shard is locked once.
+func (c *Sharded[K, V]) GetMany(keys []K) map[K]V {
+	out := make(map[K]V, len(keys))
+
+	for i, shardKeys := range c.groupKeys(keys) {
+		if len(shardKeys) == 0 {
+			continue
+		}
+
+		maps.Copy(out, c.shards[i].GetMany(shardKeys))
+	}
+
+	return out
+}
+
+// SetMany sets all the items, replacing any existing items.  Items are grouped so each shard is locked once.
+func (c *Sharded[K, V]) SetMany(items map[K]V) {
+	groups := make([]map[K]V, len(c.shards))
+
+	for k, v := range items {
+		i := c.index(k)
+		if groups[i] == nil {
+			groups[i] = make(map[K]V)
+		}
+
+		groups[i][k] = v
+	}
+
+	for i, group := range groups {
+		if len(group) > 0 {
+			c.shards[i].SetMany(group)
+		}
+	}
+}
+
+// DeleteFunc deletes all items matching the predicate, returning the count deleted.
+// Shards are processed independently, this is not atomic across shards.
+func (c *Sharded[K, V]) DeleteFunc(del func(K, V) bool) int {
+	count := 0
+
+	for _, s := range c.shards {
+		count += s.DeleteFunc(del)
+	}
+
+	return count
+}
+
+func (c *Sharded[K, V]) groupKeys(keys []K) [][]K {
+	groups := make([][]K, len(c.shards))
+
+	for _, k := range keys {
+		i := c.index(k)
+		groups[i] = append(groups[i], k)
+	}
+
+	return groups
+}
+
+// Export returns all items.
+func (c *Sharded[K, V]) Export() []Entry[K, V] {
+	var out []Entry[K, V]
+
+	for _, s := range c.shards {
+		out = append(out, s.Export()...)
+	}
+
+	return out
+}
+
+// Import adds the entries, replacing any existing items.  Expired entries are skipped.
+func (c *Sharded[K, V]) Import(entries []Entry[K, V]) {
+	groups := make([][]Entry[K, V], len(c.shards))
+
+	for _, e := range entries {
+		i := c.index(e.Key)
+		groups[i] = append(groups[i], e)
+	}
+
+	for i, group := range groups {
+		if len(group) > 0 {
+			c.shards[i].Import(group)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cache_test
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bir/iken/cache"
+)
+
+// Type assertion
+var _ cache.Cache[string, string] = cache.NewSharded[string, string](4)
+
+func TestShardedContract(t *testing.T) {
+	testCacheContract(t, cache.NewSharded[string, int](16))
+}
+
+func TestSharded(t *testing.T) {
+	c := cache.NewSharded[int, string](0)
+
+	for i := range 100 {
+		c.Set(i, strconv.Itoa(i))
+	}
+
+	assert.Equal(t, 100, c.Len())
+
+	kk := c.Keys()
+	sort.Ints(kk)
+	assert.Len(t, kk, 100)
+	assert.Equal(t, 0, kk[0])
+	assert.Equal(t, 99, kk[99])
+
+	v, ok := c.Get(42)
+	assert.True(t, ok)
+	assert.Equal(t, "42", v)
+
+	c.Delete(42)
+
+	_, ok = c.Get(42)
+	assert.False(t, ok)
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+	assert.Nil(t, c.Keys())
+}
+
+func benchmarkParallel(b *testing.B, c cache.Cache[int, int]) {
+	b.Helper()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if i%4 == 0 {
+				c.Set(i%10000, i)
+			} else {
+				c.Get(i % 10000)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkBasicParallel(b *testing.B) {
+	benchmarkParallel(b, cache.NewBasic[int, int]())
+}
+
+func BenchmarkShardedParallel(b *testing.B) {
+	benchmarkParallel(b, cache.NewSharded[int, int](32))
+}
+
+func benchmarkParallelWrites(b *testing.B, c cache.Cache[int, int]) {
+	b.Helper()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Set(i%10000, i)
+			i++
+		}
+	})
+}
+
+func BenchmarkBasicParallelWrites(b *testing.B) {
+	benchmarkParallelWrites(b, cache.NewBasic[int, int]())
+}
+
+func BenchmarkShardedParallelWrites(b *testing.B) {
+	benchmarkParallelWrites(b, cache.NewSharded[int, int](32))
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is incremented for incompatible changes to the snapshot format.
+const snapshotVersion = 1
+
+var (
+	// ErrCorruptSnapshot is returned when a snapshot can not be decoded.  The cache is not modified.
+	ErrCorruptSnapshot = errors.New("corrupt snapshot")
+	// ErrSnapshotVersion is returned when a snapshot was written with an unsupported format version.
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+)
+
+// Entry is a cache item as stored in a snapshot.  A zero Expires never expires.
+type Entry[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Expires time.Time
+}
+
+// Snapshotter is implemented by caches that can be persisted with SaveFile and restored with LoadFile.
+type Snapshotter[K comparable, V any] interface {
+	// Export returns all unexpired items.
+	Export() []Entry[K, V]
+	// Import adds the entries, replacing any existing items.  Expired entries are skipped.
+	Import(entries []Entry[K, V])
+}
+
+type snapshotHeader struct {
+	Version int
+	Created time.Time
+}
+
+// WriteSnapshot encodes the cache items to `w` using encoding/gob.  K and V must be gob encodable.
+func WriteSnapshot[K comparable, V any](w io.Writer, c Snapshotter[K, V]) error {
+	enc := gob.NewEncoder(w)
+
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Created: nowFunc()}); err != nil {
+		return fmt.Errorf("encode snapshot header: %w", err)
+	}
+
+	if err := enc.Encode(c.Export()); err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSnapshot decodes a snapshot from `r` and imports it into the cache.  The snapshot is fully decoded before
+// import, so a corrupt snapshot leaves the cache unmodified and returns ErrCorruptSnapshot.
+func ReadSnapshot[K comparable, V any](r io.Reader, c Snapshotter[K, V]) (err error) {
+	defer func() {
+		// Decoding untrusted input must never crash startup.
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", ErrCorruptSnapshot, p)
+		}
+	}()
+
+	dec := gob.NewDecoder(r)
+
+	var header snapshotHeader
+
+	if err = dec.Decode(&header); err != nil {
+		return fmt.Errorf("%w: header: %w", ErrCorruptSnapshot, err)
+	}
+
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, header.Version)
+	}
+
+	var entries []Entry[K, V]
+
+	if err = dec.Decode(&entries); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptSnapshot, err)
+	}
+
+	c.Import(entries)
+
+	return nil
+}
+
+// SaveFile writes a snapshot of the cache to `path`.  The file is written to a temporary file and renamed, so a
+// failed save never leaves a partial snapshot.
+func SaveFile[K comparable, V any](path string, c Snapshotter[K, V]) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+
+	tmp := f.Name()
+	defer os.Remove(tmp) //nolint:errcheck // Cleanup on failure, it is already renamed on success
+
+	if err = WriteSnapshot(f, c); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFile restores a snapshot from `path` into the cache.  A missing file is not an error, the cache is left
+// unmodified.  Corrupt files return ErrCorruptSnapshot, callers should log and continue with a cold cache.
+func LoadFile[K comparable, V any](path string, c Snapshotter[K, V]) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+
+	defer f.Close() //nolint:errcheck // read only
+
+	return ReadSnapshot(f, c)
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Type assertions
+var (
+	_ Snapshotter[string, string] = NewBasic[string, string]()
+	_ Snapshotter[string, string] = NewTTL[string, string](0, 0)
+	_ Snapshotter[string, string] = NewLRU[string, string](1)
+	_ Snapshotter[string, string] = NewSharded[string, string](1)
+)
+
+type snapshotValue struct {
+	Name  string
+	Count int
+}
+
+func TestSnapshotBasic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+
+	c := NewBasic[string, snapshotValue]()
+	c.Set("a", snapshotValue{"a", 1})
+	c.Set("b", snapshotValue{"b", 2})
+
+	require.NoError(t, SaveFile(path, c))
+
+	restored := NewBasic[string, snapshotValue]()
+	require.NoError(t, LoadFile(path, restored))
+
+	kk := restored.Keys()
+	sort.Strings(kk)
+	assert.Equal(t, []string{"a", "b"}, kk)
+
+	v, ok := restored.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, snapshotValue{"b", 2}, v)
+
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "temp file removed")
+}
+
+func TestSnapshotTTL(t *testing.T) {
+	now := testClock(t)
+	path := filepath.Join(t.TempDir(), "cache.gob")
+
+	c := NewTTL[string, int](time.Minute, 0)
+	c.Set("short", 1)
+	c.SetWithTTL("long", 2, time.Hour)
+	c.SetWithTTL("forever", 3, 0)
+
+	longExpires, _ := c.Expires("long")
+
+	require.NoError(t, SaveFile(path, c))
+
+	// Restart after "short" expired.
+	now.Add(int64(time.Minute))
+
+	restored := NewTTL[string, int](time.Minute, 0)
+	require.NoError(t, LoadFile(path, restored))
+
+	kk := restored.Keys()
+	sort.Strings(kk)
+	assert.Equal(t, []string{"forever", "long"}, kk)
+
+	exp, ok := restored.Expires("long")
+	assert.True(t, ok)
+	assert.Equal(t, longExpires, exp, "expiry retained")
+
+	exp, ok = restored.Expires("forever")
+	assert.True(t, ok)
+	assert.True(t, exp.IsZero())
+
+	// Basic skips expired entries.
+	basic := NewBasic[string, int]()
+	require.NoError(t, LoadFile(path, basic))
+
+	kk = basic.Keys()
+	sort.Strings(kk)
+	assert.Equal(t, []string{"forever", "long"}, kk)
+}
+
+func TestSnapshotLRU(t *testing.T) {
+	var buf bytes.Buffer
+
+	c := NewLRU[int, int](3)
+	c.Set(1, 1)
+	c.Set(2, 2)
+	c.Set(3, 3)
+	c.Get(1)
+
+	require.NoError(t, WriteSnapshot(&buf, c))
+
+	restored := NewLRU[int, int](3)
+	require.NoError(t, ReadSnapshot(&buf, restored))
+	assert.Equal(t, []int{1, 3, 2}, restored.Keys(), "usage order retained")
+}
+
+func TestSnapshotSharded(t *testing.T) {
+	var buf bytes.Buffer
+
+	c := NewSharded[int, int](4)
+	for i := range 20 {
+		c.Set(i, i)
+	}
+
+	require.NoError(t, WriteSnapshot(&buf, c))
+
+	restored := NewSharded[int, int](8)
+	require.NoError(t, ReadSnapshot(&buf, restored))
+	assert.Equal(t, 20, restored.Len())
+
+	v, ok := restored.Get(7)
+	assert.True(t, ok)
+	assert.Equal(t, 7, v)
+}
+
+func TestSnapshotMissing(t *testing.T) {
+	c := NewBasic[string, int]()
+
+	require.NoError(t, LoadFile(filepath.Join(t.TempDir(), "missing.gob"), c))
+	assert.Nil(t, c.Keys())
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	dir := t.TempDir()
+
+	var valid bytes.Buffer
+
+	src := NewBasic[string, int]()
+	src.Set("a", 1)
+	require.NoError(t, WriteSnapshot(&valid, src))
+
+	var wrongVersion bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&wrongVersion).Encode(snapshotHeader{Version: 99}))
+
+	var wrongType bytes.Buffer
+
+	other := NewBasic[int, string]()
+	other.Set(1, "a")
+	require.NoError(t, WriteSnapshot(&wrongType, other))
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, ErrCorruptSnapshot},
+		{"garbage", []byte("not a snapshot"), ErrCorruptSnapshot},
+		{"truncated", valid.Bytes()[:valid.Len()-3], ErrCorruptSnapshot},
+		{"wrong type", wrongType.Bytes(), ErrCorruptSnapshot},
+		{"version", wrongVersion.Bytes(), ErrSnapshotVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			require.NoError(t, os.WriteFile(path, tt.data, 0o600))
+
+			c := NewBasic[string, int]()
+			c.Set("existing", 1)
+
+			err := LoadFile(path, c)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, []string{"existing"}, c.Keys(), "cache unmodified")
+		})
+	}
+}
+
+func TestSaveFileError(t *testing.T) {
+	err := SaveFile(filepath.Join(t.TempDir(), "missing", "cache.gob"), NewBasic[string, int]())
+	assert.Error(t, err)
+}
@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"iter"
+	"sync"
+	"time"
+)
+
+// nowFunc is a utility used for automated testing (overriding the runtime clock).
+var nowFunc = time.Now
+
+type ttlItem[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// expired reports if the item is expired at `now`.  A zero expiry never expires.
+func (i ttlItem[V]) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// TTL is a thread safe cache with time based expiration.  Expired items are evicted lazily on access and
+// periodically by a background janitor.
+type TTL[K comparable, V any] struct {
+	*sync.RWMutex
+
+	items      map[K]ttlItem[V]
+	defaultTTL time.Duration
+	stop       chan struct{}
+	stopped    chan struct{}
+	stopOnce   sync.Once
+	onEvict    EvictFunc[K, V]
+}
+
+// NewTTL creates a new thread safe cache where items expire after `defaultTTL`.  A `defaultTTL` <= 0 disables
+// expiry for items added with Set.
+// If `cleanupInterval` > 0 a janitor go routine evicts expired items on that interval, call Stop to release it.
+func NewTTL[K comparable, V any](defaultTTL, cleanupInterval time.Duration) *TTL[K, V] {
+	c := &TTL[K, V]{
+		RWMutex:    &sync.RWMutex{},
+		items:      make(map[K]ttlItem[V]),
+		defaultTTL: defaultTTL,
+		stop:       make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		c.stopped = make(chan struct{})
+
+		go c.janitor(cleanupInterval)
+	}
+
+	return c
+}
+
+// WithEvictFunc registers a callback invoked when expired items are evicted.  It is not invoked for Delete or Clear.
+func (c *TTL[K, V]) WithEvictFunc(fn EvictFunc[K, V]) *TTL[K, V] {
+	c.onEvict = fn
+
+	return c
+}
+
+// Set sets any item to the cache, replacing any existing item, using the default TTL.
+func (c *TTL[K, V]) Set(k K, v V) {
+	c.SetWithTTL(k, v, c.defaultTTL)
+}
+
+// SetWithTTL sets any item to the cache, replacing any existing item.  The item expires after `ttl`, a `ttl` <= 0
+// never expires.
+func (c *TTL[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	item := ttlItem[V]{value: v}
+	if ttl > 0 {
+		item.expires = nowFunc().Add(ttl)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.items[k] = item
+}
+
+// Get gets an item from the cache.
+// Returns the item or zero value, and a bool indicating whether the key was found.  Expired items are evicted.
+func (c *TTL[K, V]) Get(k K) (V, bool) { //nolint:ireturn // false positive
+	c.RLock()
+	item, found := c.items[k]
+	c.RUnlock()
+
+	if !found {
+		var zero V
+
+		return zero, false
+	}
+
+	if item.expired(nowFunc()) {
+		c.evict(k)
+
+		var zero V
+
+		return zero, false
+	}
+
+	return item.value, true
+}
+
+// Expires returns the expiry time of the key.  A zero time is returned for items that never expire.
+// The bool indicates whether the key was found.
+func (c *TTL[K, V]) Expires(k K) (time.Time, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	item, found := c.items[k]
+	if !found || item.expired(nowFunc()) {
+		return time.Time{}, false
+	}
+
+	return item.expires, true
+}
+
+// Keys returns existing, unexpired keys, the order is indeterminate.
+func (c *TTL[K, V]) Keys() []K {
+	c.RLock()
+	defer c.RUnlock()
+
+	l := len(c.items)
+	if l == 0 {
+		return nil
+	}
+
+	now := nowFunc()
+	out := make([]K, 0, l)
+
+	for key, item := range c.items {
+		if !item.expired(now) {
+			out = append(out, key)
+		}
+	}
+
+	return out
+}
+
+// Len returns the count of items in the cache, including expired items not yet evicted.
+func (c *TTL[K, V]) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+
+	return len(c.items)
+}
+
+// Delete deletes the item with provided key from the cache.
+func (c *TTL[K, V]) Delete(key K) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.items, key)
+}
+
+// Clear resets the cache.
+func (c *TTL[K, V]) Clear() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.items = make(map[K]ttlItem[V])
+}
+
+// DeleteExpired evicts all expired items.
+func (c *TTL[K, V]) DeleteExpired() {
+	now := nowFunc()
+
+	var evicted []keyValue[K, V]
+
+	c.Lock()
+
+	for key, item := range c.items {
+		if item.expired(now) {
+			delete(c.items, key)
+
+			if c.onEvict != nil {
+				evicted = append(evicted, keyValue[K, V]{key: key, value: item.value})
+			}
+		}
+	}
+
+	c.Unlock()
+
+	for _, e := range evicted {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+// Stop terminates the janitor go routine and waits for it to exit.  The cache remains usable, with lazy eviction
+// only.
+func (c *TTL[K, V]) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+
+	if c.stopped != nil {
+		<-c.stopped
+	}
+}
+
+// evict removes the key only if it is still expired, guarding against a concurrent Set.
+func (c *TTL[K, V]) evict(k K) {
+	c.Lock()
+
+	item, found := c.items[k]
+	if found && item.expired(nowFunc()) {
+		delete(c.items, k)
+	} else {
+		found = false
+	}
+
+	c.Unlock()
+
+	if found && c.onEvict != nil {
+		c.onEvict(k, item.value)
+	}
+}
+
+func (c *TTL[K, V]) janitor(interval time.Duration) {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// All returns an iterator over the unexpired items in the cache, the order is indeterminate.
+// The cache is read locked during iteration, the loop body must not modify the cache.
+func (c *TTL[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.RLock()
+		defer c.RUnlock()
+
+		now := nowFunc()
+
+		for k, item := range c.items {
+			if item.expired(now) {
+				continue
+			}
+
+			if !yield(k, item.value) {
+				return
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the unexpired keys in the cache, the order is indeterminate.
+// The cache is read locked during iteration, the loop body must not modify the cache.
+func (c *TTL[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range c.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// GetMany gets the unexpired items found for the keys.
+func (c *TTL[K, V]) GetMany(keys []K) map[K]V {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := nowFunc()
+	out := make(map[K]V, len(keys))
+
+	for _, k := range keys {
+		if item, found := c.items[k]; found && !item.expired(now) {
+			out[k] = item.value
+		}
+	}
+
+	return out
+}
+
+// SetMany sets all the items using the default TTL, replacing any existing items.
+func (c *TTL[K, V]) SetMany(items map[K]V) {
+	var expires time.Time
+	if c.defaultTTL > 0 {
+		expires = nowFunc().Add(c.defaultTTL)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for k, v := range items {
+		c.items[k] = ttlItem[V]{value: v, expires: expires}
+	}
+}
+
+// DeleteFunc deletes all unexpired items matching the predicate, returning the count deleted.
+func (c *TTL[K, V]) DeleteFunc(del func(K, V) bool) int {
+	c.Lock()
+	defer c.Unlock()
+
+	now := nowFunc()
+	count := 0
+
+	for k, item := range c.items {
+		if !item.expired(now) && del(k, item.value) {
+			delete(c.items, k)
+			count++
+		}
+	}
+
+	return count
+}
+
+// Export returns all unexpired items, with their expiry.
+func (c *TTL[K, V]) Export() []Entry[K, V] {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := nowFunc()
+	out := make([]Entry[K, V], 0, len(c.items))
+
+	for k, item := range c.items {
+		if !item.expired(now) {
+			out = append(out, Entry[K, V]{Key: k, Value: item.value, Expires: item.expires})
+		}
+	}
+
+	return out
+}
+
+// Import adds the entries, replacing any existing items, retaining their original expiry.  Expired entries are
+// skipped.
+func (c *TTL[K, V]) Import(entries []Entry[K, V]) {
+	now := nowFunc()
+
+	c.Lock()
+	defer c.Unlock()
+
+	for _, e := range entries {
+		item := ttlItem[V]{value: e.Value, expires: e.Expires}
+		if !item.expired(now) {
+			c.items[e.Key] = item
+		}
+	}
+}
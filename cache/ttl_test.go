@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClock replaces nowFunc with a manually advanced clock, restoring the runtime clock on cleanup.
+func testClock(t *testing.T) *atomic.Int64 {
+	t.Helper()
+
+	var now atomic.Int64
+
+	now.Store(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+
+	nowFunc = func() time.Time {
+		return time.Unix(0, now.Load()).UTC()
+	}
+
+	t.Cleanup(func() {
+		nowFunc = time.Now
+	})
+
+	return &now
+}
+
+// Type assertion
+var _ Cache[string, string] = NewTTL[string, string](0, 0)
+
+func TestTTL(t *testing.T) {
+	now := testClock(t)
+
+	c := NewTTL[string, int](time.Minute, 0)
+	defer c.Stop()
+
+	v, ok := c.Get("a")
+	assert.Equal(t, 0, v)
+	assert.False(t, ok)
+	assert.Nil(t, c.Keys())
+
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Hour)
+	c.SetWithTTL("forever", 3, 0)
+
+	v, ok = c.Get("a")
+	assert.Equal(t, 1, v)
+	assert.True(t, ok)
+
+	exp, ok := c.Expires("a")
+	assert.True(t, ok)
+	assert.Equal(t, nowFunc().Add(time.Minute), exp)
+
+	exp, ok = c.Expires("forever")
+	assert.True(t, ok)
+	assert.True(t, exp.IsZero())
+
+	kk := c.Keys()
+	sort.Strings(kk)
+	assert.Equal(t, []string{"a", "b", "forever"}, kk)
+
+	// Expire "a"
+	now.Add(int64(time.Minute))
+
+	v, ok = c.Get("a")
+	assert.Equal(t, 0, v)
+	assert.False(t, ok)
+
+	_, ok = c.Expires("a")
+	assert.False(t, ok)
+
+	kk = c.Keys()
+	sort.Strings(kk)
+	assert.Equal(t, []string{"b", "forever"}, kk)
+
+	// Expire "b", only visible to Keys until purged
+	now.Add(int64(time.Hour))
+
+	assert.Equal(t, []string{"forever"}, c.Keys())
+
+	c.DeleteExpired()
+	c.RLock()
+	assert.Len(t, c.items, 1)
+	c.RUnlock()
+
+	c.Delete("forever")
+	assert.Nil(t, c.Keys())
+
+	c.Set("a", 1)
+	c.Clear()
+	assert.Nil(t, c.Keys())
+}
+
+func TestTTLNoDefault(t *testing.T) {
+	now := testClock(t)
+
+	c := NewTTL[string, int](0, 0)
+
+	c.Set("a", 1)
+	now.Add(int64(24 * time.Hour))
+
+	v, ok := c.Get("a")
+	assert.Equal(t, 1, v)
+	assert.True(t, ok)
+}
+
+func TestTTLJanitor(t *testing.T) {
+	now := testClock(t)
+
+	c := NewTTL[string, int](time.Minute, time.Millisecond)
+	defer c.Stop()
+
+	c.Set("a", 1)
+	now.Add(int64(time.Minute))
+
+	assert.Eventually(t, func() bool {
+		c.RLock()
+		defer c.RUnlock()
+
+		return len(c.items) == 0
+	}, time.Second, time.Millisecond)
+
+	c.Stop()
+	c.Stop() // Safe to call twice
+}
+
+func TestTTLIterableExpired(t *testing.T) {
+	now := testClock(t)
+
+	c := NewTTL[string, int](time.Minute, 0)
+
+	c.SetMany(map[string]int{"a": 1, "b": 2})
+	c.SetWithTTL("forever", 3, 0)
+
+	exp, ok := c.Expires("a")
+	assert.True(t, ok)
+	assert.Equal(t, nowFunc().Add(time.Minute), exp)
+
+	now.Add(int64(time.Minute))
+
+	assert.Equal(t, map[string]int{"forever": 3}, maps.Collect(c.All()))
+	assert.Equal(t, []string{"forever"}, slices.Collect(c.KeysSeq()))
+	assert.Equal(t, map[string]int{"forever": 3}, c.GetMany([]string{"a", "forever"}))
+	assert.Equal(t, 1, c.DeleteFunc(func(string, int) bool { return true }))
+	assert.Equal(t, 2, c.Len(), "expired items are not deleted")
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"container/list"
+	"iter"
+	"sync"
+	"time"
+)
+
+type windowEntry[K comparable] struct {
+	key    K
+	marked time.Time
+}
+
+// WindowedExists is a thread safe data structure to track usage of keys within a time window, e.g. deduplication of
+// message IDs.  Keys are forgotten `window` after they are marked, and optionally the oldest keys are dropped
+// once `maxKeys` is reached.  It supports the same operations as Exists.
+type WindowedExists[K comparable] struct {
+	*sync.Mutex
+
+	window  time.Duration
+	maxKeys int
+	items   map[K]*list.Element
+	order   *list.List // Front is the oldest mark.
+}
+
+// NewWindowedExists creates a new thread safe exists that forgets keys after `window`.  A `maxKeys` > 0 limits the
+// count of tracked keys, dropping the oldest first.
+func NewWindowedExists[K comparable](window time.Duration, maxKeys int) *WindowedExists[K] {
+	return &WindowedExists[K]{
+		Mutex:   &sync.Mutex{},
+		window:  window,
+		maxKeys: maxKeys,
+		items:   make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Mark flags the key, restarting its window.
+func (c *WindowedExists[K]) Mark(k K) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := nowFunc()
+	c.prune(now)
+
+	if e, found := c.items[k]; found {
+		e.Value.(*windowEntry[K]).marked = now //nolint:forcetypeassert // internal invariant
+		c.order.MoveToBack(e)
+
+		return
+	}
+
+	c.add(k, now)
+}
+
+// MarkIf flags the key and returns if it was previously set within the window.  An existing mark does not restart
+// the window.
+func (c *WindowedExists[K]) MarkIf(k K) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	now := nowFunc()
+	c.prune(now)
+
+	if _, found := c.items[k]; found {
+		return true
+	}
+
+	c.add(k, now)
+
+	return false
+}
+
+// Check returns true if the key is marked within the window.
+func (c *WindowedExists[K]) Check(k K) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	c.prune(nowFunc())
+
+	_, found := c.items[k]
+
+	return found
+}
+
+// Keys returns existing keys, ordered from oldest to newest mark.
+func (c *WindowedExists[K]) Keys() []K {
+	c.Lock()
+	defer c.Unlock()
+
+	c.prune(nowFunc())
+
+	l := c.order.Len()
+	if l == 0 {
+		return nil
+	}
+
+	out := make([]K, 0, l)
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		out = append(out, e.Value.(*windowEntry[K]).key) //nolint:forcetypeassert // internal invariant
+	}
+
+	return out
+}
+
+// Len returns the count of keys marked within the window.
+func (c *WindowedExists[K]) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	c.prune(nowFunc())
+
+	return c.order.Len()
+}
+
+// Delete deletes the item with provided key.
+func (c *WindowedExists[K]) Delete(key K) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, found := c.items[key]; found {
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// KeysSeq returns an iterator over the keys, ordered from oldest to newest mark.
+// The exists is locked during iteration, the loop body must not access it.
+func (c *WindowedExists[K]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.Lock()
+		defer c.Unlock()
+
+		c.prune(nowFunc())
+
+		for e := c.order.Front(); e != nil; e = e.Next() {
+			if !yield(e.Value.(*windowEntry[K]).key) { //nolint:forcetypeassert // internal invariant
+				return
+			}
+		}
+	}
+}
+
+// DeleteFunc deletes all keys matching the predicate, returning the count deleted.
+func (c *WindowedExists[K]) DeleteFunc(del func(K) bool) int {
+	c.Lock()
+	defer c.Unlock()
+
+	c.prune(nowFunc())
+
+	count := 0
+
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+
+		key := e.Value.(*windowEntry[K]).key //nolint:forcetypeassert // internal invariant
+		if del(key) {
+			c.order.Remove(e)
+			delete(c.items, key)
+			count++
+		}
+
+		e = next
+	}
+
+	return count
+}
+
+func (c *WindowedExists[K]) add(k K, now time.Time) {
+	if c.maxKeys > 0 && c.order.Len() >= c.maxKeys {
+		c.removeOldest()
+	}
+
+	c.items[k] = c.order.PushBack(&windowEntry[K]{key: k, marked: now})
+}
+
+// prune drops expired keys, marks are ordered so only the front needs checking.
+func (c *WindowedExists[K]) prune(now time.Time) {
+	cutoff := now.Add(-c.window)
+
+	for e := c.order.Front(); e != nil; e = c.order.Front() {
+		if e.Value.(*windowEntry[K]).marked.After(cutoff) { //nolint:forcetypeassert // internal invariant
+			return
+		}
+
+		c.removeOldest()
+	}
+}
+
+func (c *WindowedExists[K]) removeOldest() {
+	e := c.order.Front()
+	if e == nil {
+		return
+	}
+
+	entry := c.order.Remove(e).(*windowEntry[K]) //nolint:forcetypeassert // internal invariant
+	delete(c.items, entry.key)
+}
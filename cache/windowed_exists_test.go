@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowedExists(t *testing.T) {
+	now := testClock(t)
+
+	c := NewWindowedExists[int](time.Minute, 0)
+
+	assert.False(t, c.Check(1), "empty check")
+	assert.Nil(t, c.Keys(), "empty keys")
+
+	assert.False(t, c.MarkIf(1), "mark if not exists")
+	assert.True(t, c.MarkIf(1), "fail mark if exists")
+
+	now.Add(int64(30 * time.Second))
+	c.Mark(2)
+	assert.Equal(t, []int{1, 2}, c.Keys())
+
+	// MarkIf does not restart the window
+	assert.True(t, c.MarkIf(1))
+
+	now.Add(int64(30 * time.Second))
+	assert.False(t, c.Check(1), "expired")
+	assert.True(t, c.Check(2), "valid check")
+	assert.Equal(t, 1, c.Len())
+	assert.False(t, c.MarkIf(1), "mark if expired")
+
+	// Mark restarts the window
+	now.Add(int64(30 * time.Second))
+	c.Mark(2)
+	assert.Equal(t, []int{1, 2}, c.Keys())
+
+	now.Add(int64(30 * time.Second))
+	assert.Equal(t, []int{2}, c.Keys())
+
+	c.Delete(2)
+	c.Delete(3)
+	assert.False(t, c.Check(2))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWindowedExistsMaxKeys(t *testing.T) {
+	testClock(t)
+
+	c := NewWindowedExists[string](time.Hour, 2)
+
+	c.Mark("a")
+	c.Mark("b")
+	c.Mark("a") // "b" is now the oldest
+	c.Mark("c")
+	assert.Equal(t, []string{"a", "c"}, c.Keys())
+
+	assert.False(t, c.MarkIf("d"))
+	assert.Equal(t, []string{"c", "d"}, c.Keys())
+}
+
+func TestWindowedExistsMarkIfAtomic(t *testing.T) {
+	c := NewWindowedExists[int](time.Hour, 0)
+
+	var (
+		wg     sync.WaitGroup
+		misses atomic.Int32
+	)
+
+	for range 100 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if !c.MarkIf(42) {
+				misses.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), misses.Load())
+}
+
+func TestWindowedExistsIterable(t *testing.T) {
+	now := testClock(t)
+
+	c := NewWindowedExists[int](time.Minute, 0)
+
+	c.Mark(1)
+	now.Add(int64(30 * time.Second))
+	c.Mark(2)
+	c.Mark(3)
+	c.Mark(4)
+
+	assert.Equal(t, []int{1, 2, 3, 4}, slices.Collect(c.KeysSeq()))
+
+	for range c.KeysSeq() {
+		break
+	}
+
+	now.Add(int64(30 * time.Second))
+	assert.Equal(t, []int{2, 3, 4}, slices.Collect(c.KeysSeq()))
+
+	assert.Equal(t, 1, c.DeleteFunc(func(k int) bool { return k == 3 }))
+	assert.Equal(t, []int{2, 4}, slices.Collect(c.KeysSeq()))
+}
@@ -0,0 +1,115 @@
+package errs
+
+import (
+	"errors"
+)
+
+// Code is a machine-readable error classification.  Codes are errors, so `errors.Is(err, errs.CodeNotFound)` reports
+// whether any Error in the chain has the code.
+type Code string
+
+// Error returns the code string.
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Standard codes, see httputil.CodeStatus for the HTTP mapping.
+const (
+	CodeInvalidArgument   Code = "invalid_argument"
+	CodeUnauthenticated   Code = "unauthenticated"
+	CodePermissionDenied  Code = "permission_denied"
+	CodeNotFound          Code = "not_found"
+	CodeConflict          Code = "conflict"
+	CodeResourceExhausted Code = "resource_exhausted"
+	CodeUnavailable       Code = "unavailable"
+	CodeInternal          Code = "internal"
+)
+
+// Error is a classified error.  Message is safe to show to users, Err is the internal cause.
+type Error struct {
+	Code      Code
+	Message   string
+	Fields    map[string]any
+	Retryable bool
+	Err       error
+}
+
+// Error returns the message, code and cause.
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = string(e.Code)
+	}
+
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the Code of the error.
+func (e *Error) Is(target error) bool {
+	c, ok := target.(Code)
+
+	return ok && c == e.Code
+}
+
+// StackTrace returns the stack recorded by WithStack in the cause, so stacks are logged through Wrap.
+func (e *Error) StackTrace() []uintptr {
+	return causeStack(e.Err)
+}
+
+// New creates an error with the code and user facing message.  Unavailable and resource exhausted errors are
+// retryable.
+func New(code Code, message string) error {
+	return &Error{Code: code, Message: message, Retryable: defaultRetryable(code)}
+}
+
+// Wrap classifies `err` with the code and user facing message.  Returns nil if err is nil.
+func Wrap(err error, code Code, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Code: code, Message: message, Retryable: defaultRetryable(code), Err: err}
+}
+
+func defaultRetryable(code Code) bool {
+	return code == CodeUnavailable || code == CodeResourceExhausted
+}
+
+// CodeOf returns the code of the first Error in the chain, or an empty code if there is none.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return ""
+}
+
+// MessageOf returns the user facing message of the first Error in the chain with one.
+func MessageOf(err error) string {
+	for err != nil {
+		if e, ok := err.(*Error); ok && e.Message != "" { //nolint:errorlint // walking the chain
+			return e.Message
+		}
+
+		err = errors.Unwrap(err)
+	}
+
+	return ""
+}
+
+// IsRetryable reports whether the first Error in the chain is retryable.
+func IsRetryable(err error) bool {
+	var e *Error
+
+	return errors.As(err, &e) && e.Retryable
+}
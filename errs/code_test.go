@@ -0,0 +1,87 @@
+package errs_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bir/iken/errs"
+)
+
+func TestError(t *testing.T) {
+	cause := errors.New("no rows")
+	err := fmt.Errorf("load: %w", errs.Wrap(errs.WithStack(cause, 0), errs.CodeNotFound, "order not found"))
+
+	if got := err.Error(); got != "load: order not found: no rows" {
+		t.Errorf("Error() = %q", got)
+	}
+
+	if !errors.Is(err, errs.CodeNotFound) {
+		t.Error("expected Is CodeNotFound")
+	}
+
+	if errors.Is(err, errs.CodeConflict) {
+		t.Error("unexpected Is CodeConflict")
+	}
+
+	if !errors.Is(err, cause) {
+		t.Error("expected Is cause")
+	}
+
+	if got := errs.CodeOf(err); got != errs.CodeNotFound {
+		t.Errorf("CodeOf() = %q", got)
+	}
+
+	if got := errs.MessageOf(err); got != "order not found" {
+		t.Errorf("MessageOf() = %q", got)
+	}
+
+	if errs.IsRetryable(err) {
+		t.Error("unexpected retryable")
+	}
+
+	var e *errs.Error
+	if !errors.As(err, &e) || len(errs.ExtractStackFrame(e)) == 0 {
+		t.Error("expected the cause stack")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		err       error
+		code      errs.Code
+		message   string
+		text      string
+		retryable bool
+	}{
+		{errs.New(errs.CodeUnavailable, "try later"), errs.CodeUnavailable, "try later", "try later", true},
+		{errs.New(errs.CodeResourceExhausted, ""), errs.CodeResourceExhausted, "", "resource_exhausted", true},
+		{errs.New(errs.CodeInvalidArgument, "bad id"), errs.CodeInvalidArgument, "bad id", "bad id", false},
+		{&errs.Error{Code: errs.CodeConflict, Retryable: true}, errs.CodeConflict, "", "conflict", true},
+		{errs.WithStack(errs.New(errs.CodePermissionDenied, "no"), 0), errs.CodePermissionDenied, "no", "no", false},
+		{errors.New("plain"), "", "", "plain", false},
+		{nil, "", "", "", false},
+	}
+
+	for _, test := range tests {
+		if got := errs.CodeOf(test.err); got != test.code {
+			t.Errorf("CodeOf(%v) = %q, want %q", test.err, got, test.code)
+		}
+
+		if got := errs.MessageOf(test.err); got != test.message {
+			t.Errorf("MessageOf(%v) = %q, want %q", test.err, got, test.message)
+		}
+
+		if got := errs.IsRetryable(test.err); got != test.retryable {
+			t.Errorf("IsRetryable(%v) = %v, want %v", test.err, got, test.retryable)
+		}
+
+		if test.err != nil && test.err.Error() != test.text {
+			t.Errorf("Error() = %q, want %q", test.err.Error(), test.text)
+		}
+	}
+
+	if errs.Wrap(nil, errs.CodeInternal, "x") != nil {
+		t.Error("expected nil")
+	}
+}
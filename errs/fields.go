@@ -0,0 +1,110 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+type fieldsError struct {
+	err    error
+	fields map[string]any
+}
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (e *fieldsError) Unwrap() error {
+	return e.err
+}
+
+// Error directly returns the wrapped error's Error string.
+func (e *fieldsError) Error() string {
+	return e.err.Error()
+}
+
+// StackTrace returns the stack recorded by WithStack in the wrapped error, so stacks are logged through With.
+func (e *fieldsError) StackTrace() []uintptr {
+	return causeStack(e.err)
+}
+
+// With attaches the key/value pairs to `err` as log fields, see Fields.  Keys that are not strings are formatted with
+// fmt.Sprint, a trailing key without a value is given a nil value.  Returns nil if err is nil.
+//
+// example: errs.With(err, "user_id", userID, "order_id", orderID)
+func With(err error, kv ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	fields := make(map[string]any, (len(kv)+1)/2) //nolint:mnd // pairs
+
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+
+		var value any
+		if i+1 < len(kv) {
+			value = kv[i+1]
+		}
+
+		fields[key] = value
+	}
+
+	return &fieldsError{err: err, fields: fields}
+}
+
+// Fields collects the fields attached by With and Error.Fields from the whole error chain, including joined errors.
+// When a key is set more than once the outermost value is used.  Returns nil if there are no fields.
+func Fields(err error) map[string]any {
+	out := make(map[string]any)
+
+	collectFields(err, out)
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+func collectFields(err error, out map[string]any) {
+	if err == nil {
+		return
+	}
+
+	var fields map[string]any
+
+	switch e := err.(type) { //nolint:errorlint // walking the chain
+	case *fieldsError:
+		fields = e.fields
+	case *Error:
+		fields = e.Fields
+	}
+
+	for k, v := range fields {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // walking the chain
+		for _, inner := range joined.Unwrap() {
+			collectFields(inner, out)
+		}
+
+		return
+	}
+
+	collectFields(errors.Unwrap(err), out)
+}
+
+// causeStack returns the first stack recorded by WithStack in the chain.
+func causeStack(err error) []uintptr {
+	var st interface{ StackTrace() []uintptr }
+
+	if errors.As(err, &st) {
+		return st.StackTrace()
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package errs_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bir/iken/errs"
+)
+
+func TestFields(t *testing.T) {
+	cause := errors.New("no rows")
+	inner := errs.With(errs.WithStack(cause, 0), "order_id", 42, "user_id", "inner")
+	coded := &errs.Error{Code: errs.CodeNotFound, Fields: map[string]any{"table": "orders"}, Err: inner}
+
+	tests := []struct {
+		name string
+		err  error
+		want map[string]any
+	}{
+		{"nil", nil, nil},
+		{"none", cause, nil},
+		{"with", inner, map[string]any{"order_id": 42, "user_id": "inner"}},
+		{
+			"chain outer wins",
+			errs.With(fmt.Errorf("load: %w", coded), "user_id", "outer"),
+			map[string]any{"order_id": 42, "user_id": "outer", "table": "orders"},
+		},
+		{
+			"joined",
+			errors.Join(errs.With(cause, "a", 1), errs.With(cause, "b", 2)),
+			map[string]any{"a": 1, "b": 2},
+		},
+		{"odd and non string keys", errs.With(cause, 1, "one", "dangling"), map[string]any{"1": "one", "dangling": nil}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := errs.Fields(test.err); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Fields() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	if errs.With(nil, "key", "value") != nil {
+		t.Error("expected nil")
+	}
+
+	cause := errors.New("no rows")
+	err := errs.With(errs.WithStack(cause, 0), "key", "value")
+
+	if err.Error() != "no rows" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	if !errors.Is(err, cause) {
+		t.Error("expected Is cause")
+	}
+
+	if len(errs.ExtractStackFrame(err)) == 0 {
+		t.Error("expected the cause stack")
+	}
+}
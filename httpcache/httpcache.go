@@ -0,0 +1,370 @@
+// Package httpcache provides HTTP response caching middleware backed by any cache.Cache.
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bir/iken/cache"
+	"github.com/bir/iken/chain"
+	"github.com/bir/iken/httputil"
+)
+
+// MaxBodySize controls the maximum response body that can be cached.  Anything greater is not cached.
+var MaxBodySize = 1024 * 1024
+
+// now is a utility used for automated testing (overriding the runtime clock).
+var now = time.Now
+
+// CachedResponse is a response captured by Middleware.  Responses with a Vary header are stored as a marker entry
+// with only Vary and Expires set, pointing to the variant entries keyed by the varied request headers.
+type CachedResponse struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Stored  time.Time
+	Expires time.Time
+	Vary    []string
+}
+
+func (c CachedResponse) fresh(t time.Time) bool {
+	return t.Before(c.Expires)
+}
+
+// Middleware caches GET and HEAD responses in `c`.
+//
+// Only 200 responses with a positive Cache-Control max-age (or s-maxage) are cached, no-store, private,
+// Vary: * and Set-Cookie responses are never cached.  Requests with an Authorization header only use and store
+// responses marked public or with s-maxage, see RFC 9111 section 3.5.
+// The cache key is built from the method, path, query sorted by key and the request headers named in the response
+// Vary header.
+// Requests with Cache-Control no-store bypass the cache, no-cache skips the lookup but stores the response.
+// Cached responses answer conditional requests (If-None-Match, If-Modified-Since) with 304 Not Modified.
+func Middleware(c cache.Cache[string, CachedResponse]) chain.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			directives := parseCacheControl(r.Header.Get("Cache-Control"))
+			if _, ok := directives["no-store"]; ok {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			key := baseKey(r)
+
+			if _, ok := directives["no-cache"]; !ok {
+				if resp, ok := lookup(c, key, r); ok && (!authorized(r) || sharedAuthorized(resp.Header)) {
+					serve(w, r, resp)
+
+					return
+				}
+			}
+
+			capture(c, key, next, w, r)
+		})
+	}
+}
+
+// baseKey builds the cache key for the request, ignoring Vary.
+func baseKey(r *http.Request) string {
+	return r.Method + " " + r.URL.EscapedPath() + "?" + sortedQuery(r.URL.Query())
+}
+
+// sortedQuery sorts the query by key, the order of repeated values is significant and kept.
+func sortedQuery(q url.Values) string {
+	return q.Encode() // Encode sorts by key
+}
+
+// variantKey extends the base key with the request values of the varied headers.
+func variantKey(key string, vary []string, r *http.Request) string {
+	var b strings.Builder
+
+	b.WriteString(key)
+
+	for _, h := range vary {
+		b.WriteString("\n")
+		b.WriteString(h)
+		b.WriteString(":")
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+
+	return b.String()
+}
+
+// lookup returns the fresh cached response for the request, evicting stale entries.
+func lookup(c cache.Cache[string, CachedResponse], key string, r *http.Request) (CachedResponse, bool) {
+	t := now()
+
+	resp, ok := c.Get(key)
+	if !ok {
+		return resp, false
+	}
+
+	if !resp.fresh(t) {
+		c.Delete(key)
+
+		return CachedResponse{}, false
+	}
+
+	if len(resp.Vary) == 0 {
+		return resp, true
+	}
+
+	key = variantKey(key, resp.Vary, r)
+
+	resp, ok = c.Get(key)
+	if !ok {
+		return resp, false
+	}
+
+	if !resp.fresh(t) {
+		c.Delete(key)
+
+		return CachedResponse{}, false
+	}
+
+	return resp, true
+}
+
+// serve writes the cached response, or 304 Not Modified for matching conditional requests.
+func serve(w http.ResponseWriter, r *http.Request, resp CachedResponse) {
+	h := w.Header()
+	for k, v := range resp.Header {
+		h[k] = slices.Clone(v)
+	}
+
+	h.Set("Age", strconv.Itoa(int(now().Sub(resp.Stored).Seconds())))
+
+	if notModified(r, resp.Header) {
+		h.Del(httputil.ContentType)
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	w.WriteHeader(resp.Status)
+
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(resp.Body)
+	}
+}
+
+// notModified evaluates the request preconditions against the cached response headers.
+// If-None-Match takes precedence over If-Modified-Since, see RFC 9110 section 13.2.2.
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := h.Get("ETag")
+		if etag == "" {
+			return false
+		}
+
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakMatch(candidate, etag) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	lastModified, err := http.ParseTime(h.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.After(ims)
+}
+
+func weakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// capture serves the request with `next`, storing cacheable responses.
+func capture(c cache.Cache[string, CachedResponse], key string, next http.Handler, w http.ResponseWriter,
+	r *http.Request,
+) {
+	body := &limitedBuffer{limit: MaxBodySize}
+	wrapped := httputil.WrapWriter(w)
+	wrapped.Tee(body)
+
+	before := w.Header().Clone()
+
+	next.ServeHTTP(wrapped, r)
+
+	status := wrapped.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if status != http.StatusOK || body.overflow {
+		return
+	}
+
+	header := setHeaders(before, w.Header())
+
+	ttl, ok := responseTTL(header)
+	if !ok || authorized(r) && !sharedAuthorized(header) {
+		return
+	}
+
+	stored := now()
+	resp := CachedResponse{
+		Status:  status,
+		Header:  header,
+		Stored:  stored,
+		Expires: stored.Add(ttl),
+	}
+
+	if r.Method != http.MethodHead {
+		resp.Body = body.Bytes()
+	}
+
+	vary := varyHeaders(header)
+	if slices.Contains(vary, "*") {
+		return
+	}
+
+	if len(vary) > 0 {
+		c.Set(key, CachedResponse{Vary: vary, Stored: stored, Expires: resp.Expires})
+		key = variantKey(key, vary, r)
+	}
+
+	c.Set(key, resp)
+}
+
+// responseTTL returns the shared cache lifetime of the response.
+func responseTTL(h http.Header) (time.Duration, bool) {
+	directives := parseCacheControl(h.Get("Cache-Control"))
+
+	if _, ok := directives["no-store"]; ok {
+		return 0, false
+	}
+
+	if _, ok := directives["private"]; ok {
+		return 0, false
+	}
+
+	// Cookies are per client, never share them.
+	if len(h.Values("Set-Cookie")) > 0 {
+		return 0, false
+	}
+
+	maxAge, ok := directives["s-maxage"]
+	if !ok {
+		maxAge, ok = directives["max-age"]
+	}
+
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(maxAge)
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// setHeaders returns the headers added or changed since `before`.  Headers already set by outer handlers, such as a
+// request ID, belong to the current request and are not cached.
+func setHeaders(before, after http.Header) http.Header {
+	out := make(http.Header)
+
+	for k, v := range after {
+		if !slices.Equal(v, before[k]) {
+			out[k] = slices.Clone(v)
+		}
+	}
+
+	return out
+}
+
+func authorized(r *http.Request) bool {
+	return r.Header.Get("Authorization") != ""
+}
+
+// sharedAuthorized reports whether the response may be shared for requests with an Authorization header.
+func sharedAuthorized(h http.Header) bool {
+	directives := parseCacheControl(h.Get("Cache-Control"))
+
+	_, public := directives["public"]
+	_, sMaxAge := directives["s-maxage"]
+
+	return public || sMaxAge
+}
+
+func varyHeaders(h http.Header) []string {
+	var out []string
+
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				out = append(out, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	slices.Sort(out)
+
+	return slices.Compact(out)
+}
+
+// parseCacheControl splits the Cache-Control header into lower case directives and their optional values.
+func parseCacheControl(v string) map[string]string {
+	out := make(map[string]string)
+
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(part, "=")
+		out[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	return out
+}
+
+// limitedBuffer buffers writes up to limit, beyond that the buffer is discarded and flagged as overflow.
+type limitedBuffer struct {
+	bytes.Buffer
+
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+
+	if b.Len()+len(p) > b.limit {
+		b.overflow = true
+		b.Reset()
+
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p) //nolint:wrapcheck // just a proxy
+}
@@ -0,0 +1,334 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bir/iken/cache"
+	"github.com/bir/iken/chain"
+)
+
+var startNow = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type testHandler struct {
+	calls        atomic.Int32
+	cacheControl string
+	status       int
+	header       http.Header
+}
+
+func (h *testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := h.calls.Add(1)
+
+	for k, v := range h.header {
+		w.Header()[k] = v
+	}
+
+	if h.cacheControl != "" {
+		w.Header().Set("Cache-Control", h.cacheControl)
+	}
+
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+
+	_, _ = io.WriteString(w, r.URL.Path+":"+r.Header.Get("Accept-Language")+":"+string('0'+rune(n)))
+}
+
+type result struct {
+	status int
+	body   string
+	header http.Header
+}
+
+func do(h http.Handler, method, target string, header http.Header) result {
+	r := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	b, _ := io.ReadAll(w.Result().Body)
+
+	return result{w.Code, string(b), w.Result().Header}
+}
+
+func setup(t *testing.T, next http.Handler) (http.Handler, *cache.Basic[string, CachedResponse]) {
+	t.Helper()
+
+	now = func() time.Time { return startNow }
+
+	t.Cleanup(func() {
+		now = time.Now
+	})
+
+	c := cache.NewBasic[string, CachedResponse]()
+
+	return chain.New(Middleware(c)).Handler(next), c
+}
+
+func TestMiddleware(t *testing.T) {
+	next := &testHandler{cacheControl: "public, max-age=60", header: http.Header{"Etag": {`"v1"`}}}
+	h, c := setup(t, next)
+
+	got := do(h, http.MethodGet, "/a?y=2&x=1", nil)
+	assert.Equal(t, http.StatusOK, got.status)
+	assert.Equal(t, "/a::1", got.body)
+
+	// Hit, query order is normalized
+	got = do(h, http.MethodGet, "/a?x=1&y=2", nil)
+	assert.Equal(t, http.StatusOK, got.status)
+	assert.Equal(t, "/a::1", got.body)
+	assert.Equal(t, "0", got.header.Get("Age"))
+	assert.Equal(t, `"v1"`, got.header.Get("ETag"))
+	assert.Equal(t, int32(1), next.calls.Load())
+
+	// Different query
+	got = do(h, http.MethodGet, "/a?x=2", nil)
+	assert.Equal(t, "/a::2", got.body)
+
+	// HEAD is cached separately and has no body
+	do(h, http.MethodHead, "/a", nil)
+	got = do(h, http.MethodHead, "/a", nil)
+	assert.Equal(t, "", got.body)
+	assert.Equal(t, int32(3), next.calls.Load())
+
+	// Other methods are not cached
+	do(h, http.MethodPost, "/a?x=1&y=2", nil)
+	do(h, http.MethodPost, "/a?x=1&y=2", nil)
+	assert.Equal(t, int32(5), next.calls.Load())
+
+	// Expiry
+	now = func() time.Time { return startNow.Add(30 * time.Second) }
+	got = do(h, http.MethodGet, "/a?x=1&y=2", nil)
+	assert.Equal(t, "/a::1", got.body)
+	assert.Equal(t, "30", got.header.Get("Age"))
+
+	now = func() time.Time { return startNow.Add(time.Minute) }
+	got = do(h, http.MethodGet, "/a?x=1&y=2", nil)
+	assert.Equal(t, "/a::6", got.body)
+	assert.Len(t, c.Keys(), 3)
+}
+
+func TestMiddlewareRepeatedQuery(t *testing.T) {
+	next := &testHandler{cacheControl: "max-age=60"}
+	h, _ := setup(t, next)
+
+	assert.Equal(t, "/a::1", do(h, http.MethodGet, "/a?id=2&id=1", nil).body)
+	assert.Equal(t, "/a::2", do(h, http.MethodGet, "/a?id=1&id=2", nil).body, "value order is significant")
+}
+
+func TestMiddlewareOuterHeaders(t *testing.T) {
+	next := &testHandler{cacheControl: "max-age=60"}
+	h, _ := setup(t, next)
+
+	var id atomic.Int32
+
+	// Outer middleware sets a per request header before the cache.
+	outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", string('0'+rune(id.Add(1))))
+		h.ServeHTTP(w, r)
+	})
+
+	got := do(outer, http.MethodGet, "/a", nil)
+	assert.Equal(t, "1", got.header.Get("X-Request-Id"))
+
+	got = do(outer, http.MethodGet, "/a", nil)
+	assert.Equal(t, "/a::1", got.body)
+	assert.Equal(t, "2", got.header.Get("X-Request-Id"), "not replayed from the cache")
+	assert.Equal(t, "max-age=60", got.header.Get("Cache-Control"))
+}
+
+func TestMiddlewareRequestDirectives(t *testing.T) {
+	next := &testHandler{cacheControl: "max-age=60"}
+	h, _ := setup(t, next)
+
+	noStore := http.Header{"Cache-Control": {"no-store"}}
+	noCache := http.Header{"Cache-Control": {"no-cache"}}
+
+	assert.Equal(t, "/a::1", do(h, http.MethodGet, "/a", noStore).body)
+	assert.Equal(t, "/a::2", do(h, http.MethodGet, "/a", noStore).body)
+
+	assert.Equal(t, "/a::3", do(h, http.MethodGet, "/a", nil).body)
+	assert.Equal(t, "/a::3", do(h, http.MethodGet, "/a", nil).body)
+
+	// no-cache refreshes the stored response
+	assert.Equal(t, "/a::4", do(h, http.MethodGet, "/a", noCache).body)
+	assert.Equal(t, "/a::4", do(h, http.MethodGet, "/a", nil).body)
+}
+
+func TestMiddlewareNotCacheable(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		status       int
+		header       http.Header
+	}{
+		{"no cache control", "", 0, nil},
+		{"no-store", "no-store, max-age=60", 0, nil},
+		{"private", "private, max-age=60", 0, nil},
+		{"zero max-age", "max-age=0", 0, nil},
+		{"invalid max-age", "max-age=abc", 0, nil},
+		{"status", "max-age=60", http.StatusNotFound, nil},
+		{"vary all", "max-age=60", 0, http.Header{"Vary": {"*"}}},
+		{"set cookie", "public, max-age=60", 0, http.Header{"Set-Cookie": {"session=alice"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &testHandler{cacheControl: tt.cacheControl, status: tt.status, header: tt.header}
+			h, c := setup(t, next)
+
+			do(h, http.MethodGet, "/a", nil)
+			do(h, http.MethodGet, "/a", nil)
+			assert.Equal(t, int32(2), next.calls.Load())
+			assert.Empty(t, c.Keys())
+		})
+	}
+}
+
+func TestMiddlewareSMaxAge(t *testing.T) {
+	next := &testHandler{cacheControl: `max-age=0, s-maxage="60"`}
+	h, _ := setup(t, next)
+
+	do(h, http.MethodGet, "/a", nil)
+	do(h, http.MethodGet, "/a", nil)
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func TestMiddlewareAuthorization(t *testing.T) {
+	alice := http.Header{"Authorization": {"Bearer alice"}}
+	bob := http.Header{"Authorization": {"Bearer bob"}}
+
+	next := &testHandler{cacheControl: "max-age=60"}
+	h, c := setup(t, next)
+
+	assert.Equal(t, "/me::1", do(h, http.MethodGet, "/me", alice).body)
+	assert.Equal(t, "/me::2", do(h, http.MethodGet, "/me", bob).body)
+	assert.Empty(t, c.Keys())
+
+	// Responses stored for anonymous requests are not served to authorized requests
+	assert.Equal(t, "/me::3", do(h, http.MethodGet, "/me", nil).body)
+	assert.Equal(t, "/me::3", do(h, http.MethodGet, "/me", nil).body)
+	assert.Equal(t, "/me::4", do(h, http.MethodGet, "/me", alice).body)
+
+	for _, cacheControl := range []string{"public, max-age=60", "s-maxage=60"} {
+		t.Run(cacheControl, func(t *testing.T) {
+			next := &testHandler{cacheControl: cacheControl}
+			h, _ := setup(t, next)
+
+			assert.Equal(t, "/a::1", do(h, http.MethodGet, "/a", alice).body)
+			assert.Equal(t, "/a::1", do(h, http.MethodGet, "/a", bob).body)
+			assert.Equal(t, "/a::1", do(h, http.MethodGet, "/a", nil).body)
+		})
+	}
+}
+
+func TestMiddlewareMaxBodySize(t *testing.T) {
+	defer func(size int) { MaxBodySize = size }(MaxBodySize)
+
+	MaxBodySize = 3
+
+	next := &testHandler{cacheControl: "max-age=60"}
+	h, c := setup(t, next)
+
+	assert.Equal(t, "/a::1", do(h, http.MethodGet, "/a", nil).body)
+	assert.Equal(t, "/a::2", do(h, http.MethodGet, "/a", nil).body)
+	assert.Empty(t, c.Keys())
+}
+
+func TestMiddlewareVary(t *testing.T) {
+	next := &testHandler{cacheControl: "max-age=60", header: http.Header{"Vary": {"accept-language, Accept-Language"}}}
+	h, c := setup(t, next)
+
+	en := http.Header{"Accept-Language": {"en"}}
+	fr := http.Header{"Accept-Language": {"fr"}}
+
+	assert.Equal(t, "/a:en:1", do(h, http.MethodGet, "/a", en).body)
+	assert.Equal(t, "/a:fr:2", do(h, http.MethodGet, "/a", fr).body)
+	assert.Equal(t, "/a:en:1", do(h, http.MethodGet, "/a", en).body)
+	assert.Equal(t, "/a:fr:2", do(h, http.MethodGet, "/a", fr).body)
+	assert.Equal(t, "/a::3", do(h, http.MethodGet, "/a", nil).body)
+	assert.Equal(t, int32(3), next.calls.Load())
+
+	marker, ok := c.Get("GET /a?")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Accept-Language"}, marker.Vary)
+
+	// Expired variant
+	c.Set("GET /a?\nAccept-Language:en", CachedResponse{Expires: startNow})
+	assert.Equal(t, "/a:en:4", do(h, http.MethodGet, "/a", en).body)
+
+	// Expired marker
+	now = func() time.Time { return startNow.Add(time.Minute) }
+	assert.Equal(t, "/a:fr:5", do(h, http.MethodGet, "/a", fr).body)
+}
+
+func TestMiddlewareConditional(t *testing.T) {
+	lastModified := startNow.Add(-time.Hour).Format(http.TimeFormat)
+	next := &testHandler{
+		cacheControl: "max-age=60",
+		header: http.Header{
+			"Etag":          {`W/"v1"`},
+			"Last-Modified": {lastModified},
+			"Content-Type":  {"text/plain"},
+		},
+	}
+	h, _ := setup(t, next)
+
+	do(h, http.MethodGet, "/a", nil)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"etag", http.Header{"If-None-Match": {`"v1"`}}, http.StatusNotModified},
+		{"etag list", http.Header{"If-None-Match": {`"v0", W/"v1"`}}, http.StatusNotModified},
+		{"etag any", http.Header{"If-None-Match": {`*`}}, http.StatusNotModified},
+		{"etag mismatch", http.Header{"If-None-Match": {`"v2"`}}, http.StatusOK},
+		{"etag precedence", http.Header{"If-None-Match": {`"v2"`}, "If-Modified-Since": {lastModified}}, http.StatusOK},
+		{"modified since", http.Header{"If-Modified-Since": {lastModified}}, http.StatusNotModified},
+		{"modified later", http.Header{"If-Modified-Since": {startNow.Add(-2 * time.Hour).Format(http.TimeFormat)}}, http.StatusOK},
+		{"modified invalid", http.Header{"If-Modified-Since": {"yesterday"}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := do(h, http.MethodGet, "/a", tt.header)
+			assert.Equal(t, tt.status, got.status)
+
+			if tt.status == http.StatusNotModified {
+				assert.Empty(t, got.body)
+				assert.Empty(t, got.header.Get("Content-Type"))
+				assert.Equal(t, `W/"v1"`, got.header.Get("ETag"))
+			} else {
+				assert.True(t, strings.HasPrefix(got.body, "/a::"), got.body)
+			}
+		})
+	}
+
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func TestMiddlewareConditionalNoValidators(t *testing.T) {
+	next := &testHandler{cacheControl: "max-age=60"}
+	h, _ := setup(t, next)
+
+	do(h, http.MethodGet, "/a", nil)
+
+	got := do(h, http.MethodGet, "/a", http.Header{"If-None-Match": {`"v1"`}})
+	assert.Equal(t, http.StatusOK, got.status)
+
+	got = do(h, http.MethodGet, "/a", http.Header{"If-Modified-Since": {startNow.Format(http.TimeFormat)}})
+	assert.Equal(t, http.StatusOK, got.status)
+}
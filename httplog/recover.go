@@ -10,6 +10,7 @@ import (
 
 	"github.com/rs/zerolog"
 
+	"github.com/bir/iken/errs"
 	"github.com/bir/iken/httputil"
 )
 
@@ -53,7 +54,8 @@ func LogRecoverError(ctx context.Context, stackSkip int, recoverErr any) {
 
 	s := string(debug.Stack())
 
-	zerolog.Ctx(ctx).Err(err).Ctx(ctx).Strs(httputil.LogStack, SimplifyStack(s, stackSkip+1)).Msg("Panic")
+	zerolog.Ctx(ctx).Err(err).Ctx(ctx).Fields(errs.Fields(err)).
+		Strs(httputil.LogStack, SimplifyStack(s, stackSkip+1)).Msg("Panic")
 }
 
 var RecoverBasePath = initBasePath()
@@ -124,7 +126,11 @@ func SimplifyStack(stack string, skip int) []string {
 		}
 
 		if i%2 == 0 {
-			funcName = s[0:strings.LastIndex(s, "(")]
+			// "created by" lines have no arguments.
+			funcName = s
+			if idx := strings.LastIndex(s, "("); idx >= 0 {
+				funcName = s[:idx]
+			}
 
 			continue
 		}
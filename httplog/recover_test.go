@@ -2,6 +2,7 @@ package httplog
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/bir/iken/errs"
 	"github.com/bir/iken/logctx"
 )
 
@@ -24,9 +26,9 @@ func TestRecover(t *testing.T) {
 		next          http.Handler
 		wantFirstLine string
 	}{
-		{"panic String", "123", readPanic("test"), "./recover_test.go:65 (iken/httplog.TestRecover.readPanic.func2)"},
-		{"panic Error", "123", readPanic(errors.New("test")), "./recover_test.go:65 (iken/httplog.TestRecover.readPanic.func3)"},
-		{"panic other", "123", readPanic(1), "./recover_test.go:65 (iken/httplog.TestRecover.readPanic.func4)"},
+		{"panic String", "123", readPanic("test"), "./recover_test.go:67 (iken/httplog.TestRecover.readPanic.func2)"},
+		{"panic Error", "123", readPanic(errors.New("test")), "./recover_test.go:67 (iken/httplog.TestRecover.readPanic.func3)"},
+		{"panic other", "123", readPanic(1), "./recover_test.go:67 (iken/httplog.TestRecover.readPanic.func4)"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -65,3 +67,23 @@ func readPanic(result any) http.HandlerFunc {
 		panic(result)
 	}
 }
+
+func TestLogRecoverErrorFields(t *testing.T) {
+	logOutput := bytes.NewBuffer(nil)
+	ctx := zerolog.New(logOutput).WithContext(context.Background())
+
+	LogRecoverError(ctx, 0, errs.With(errors.New("test"), "user_id", 7))
+
+	result := make(map[string]any)
+	assert.Nil(t, json.Unmarshal(logOutput.Bytes(), &result), "json Unmarshal")
+	assert.Equal(t, "test", result["error"])
+	assert.Equal(t, float64(7), result["user_id"])
+}
+
+func TestSimplifyStackCreatedBy(t *testing.T) {
+	stack := "goroutine 9 [running]:\n" +
+		"main.work(0x1)\n\t/src/main.go:10 +0x1\n" +
+		"created by main.start.func1 in goroutine 7\n\t/src/main.go:5 +0x2\n"
+
+	assert.Len(t, SimplifyStack(stack, 0), 2)
+}
@@ -40,6 +40,19 @@ func (e CustomResponseError) Error() string {
 	return http.StatusText(e.Code)
 }
 
+// CodeStatus maps error codes to the HTTP status returned by ErrorHandler.  Add entries for custom codes, unmapped
+// codes are handled as internal errors.
+var CodeStatus = map[errs.Code]int{
+	errs.CodeInvalidArgument:   http.StatusBadRequest,
+	errs.CodeUnauthenticated:   http.StatusUnauthorized,
+	errs.CodePermissionDenied:  http.StatusForbidden,
+	errs.CodeNotFound:          http.StatusNotFound,
+	errs.CodeConflict:          http.StatusConflict,
+	errs.CodeResourceExhausted: http.StatusTooManyRequests,
+	errs.CodeUnavailable:       http.StatusServiceUnavailable,
+	errs.CodeInternal:          http.StatusInternalServerError,
+}
+
 // ErrorHandler provides some standard handling for errors in an http request
 // flow.
 //
@@ -51,6 +64,10 @@ func (e CustomResponseError) Error() string {
 // AuthError to "Forbidden" or "Unauthorized" as defined by the err instance.  In addition
 // ErrBasicAuthenticate issues a basic auth challenge using default realm of "Restricted".
 // To override handle in your custom error handlers instead.
+// errs.Error to the status in CodeStatus, body is the JSON of the error message
+// if set.  Internal errors never expose the message.
+//
+// Fields attached with errs.With are added to the log ctx.
 //
 // Unhandled errors are added to the ctx and return "Internal Server Error" with
 // the request ID to aid with troubleshooting.
@@ -61,6 +78,10 @@ func ErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
 
 	logctx.AddStrToContext(r.Context(), LogErrorMessage, err.Error())
 
+	if fields := errs.Fields(err); fields != nil {
+		logctx.AddMapToContext(r.Context(), fields)
+	}
+
 	if stack := errs.MarshalStack(err); stack != nil {
 		logctx.AddToContext(r.Context(), LogStack, stack)
 	}
@@ -69,6 +90,7 @@ func ErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
 		customErr      CustomResponseError
 		validationErrs *validation.Errors
 		validationErr  validation.Error
+		codedErr       *errs.Error
 	)
 
 	switch {
@@ -107,16 +129,41 @@ func ErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
 		JSONWrite(w, r, http.StatusBadRequest,
 			ClientValidationError{http.StatusBadRequest, validationErr.UserError(), nil})
 
+	case errors.As(err, &codedErr):
+		codeError(w, r, err, codedErr.Code)
+
 	default:
 		HTTPInternalServerError(w, r)
 	}
 }
 
+func codeError(w http.ResponseWriter, r *http.Request, err error, code errs.Code) {
+	logctx.AddStrToContext(r.Context(), LogErrorCode, string(code))
+
+	status, ok := CodeStatus[code]
+	if !ok || status == http.StatusInternalServerError {
+		HTTPInternalServerError(w, r)
+
+		return
+	}
+
+	message := errs.MessageOf(err)
+	if message == "" {
+		HTTPError(w, status)
+
+		return
+	}
+
+	JSONWrite(w, r, status, ClientValidationError{status, message, nil})
+}
+
 const (
 	// LogErrorMessage is used to report internal errors to the logging service.
 	LogErrorMessage = "error.message"
 	// LogStack is used to report available error stacks to logging.
 	LogStack = "error.stack"
+	// LogErrorCode is used to report the errs.Code of classified errors to logging.
+	LogErrorCode = "error.code"
 
 	RequestIDHeader = "X-Request-Id"
 
@@ -55,6 +55,11 @@ func TestErrorHandler(t *testing.T) {
 		{"custom response", context.Background(), httputil.CustomResponseError{Code: 503}, "", 503, "Service Unavailable\n", "Service Unavailable"},
 		{"custom response text", context.Background(), httputil.CustomResponseError{Code: 503, Body: "wait"}, "", 503, "wait\n", "Service Unavailable"},
 		{"custom response source", context.Background(), httputil.CustomResponseError{Code: 503, Body: "wait", Source: httputil.ErrNotFound}, "", 503, "wait\n", "not found"},
+		{"code not found", context.Background(), errs.Wrap(errors.New("no rows"), errs.CodeNotFound, "order not found"), "", 404, `{"code":404,"message":"order not found"}`, "order not found: no rows"},
+		{"code no message", context.Background(), errs.WithStack(errs.New(errs.CodeConflict, ""), 0), "", 409, "Conflict\n", "conflict"},
+		{"code wrapped", context.Background(), fmt.Errorf("wrap:%w", errs.New(errs.CodeUnavailable, "try later")), "", 503, `{"code":503,"message":"try later"}`, "wrap:try later"},
+		{"code internal", context.Background(), errs.New(errs.CodeInternal, "secret"), "", 500, "Internal Server Error\n", "secret"},
+		{"code unmapped", context.Background(), errs.New("custom", "secret"), "", 500, "Internal Server Error\n", "secret"},
 		{"custom response json", context.Background(), httputil.CustomResponseError{Code: 503, Body: httputil.ClientValidationError{Code: 42, Message: "nope"}, Source: httputil.ErrNotFound}, "", 503, `{"code":42,"message":"nope"}`, "not found"},
 	}
 
@@ -88,3 +93,25 @@ func TestErrorHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorHandlerFields(t *testing.T) {
+	logOutput := bytes.NewBuffer(nil)
+
+	c := zerolog.New(logOutput).WithContext(context.Background())
+	r := httptest.NewRequest("FOO", "/BAR", nil).WithContext(c)
+	w := httptest.NewRecorder()
+
+	err := errs.With(errs.Wrap(errs.With(errors.New("no rows"), "order_id", "o1"), errs.CodeNotFound, "order not found"),
+		"user_id", "u1")
+	httputil.ErrorHandler(w, r, err)
+
+	zerolog.Ctx(c).Log().Msg("test")
+
+	assert.Equal(t, 404, w.Code)
+
+	var log map[string]any
+	assert.NoError(t, json.Unmarshal(logOutput.Bytes(), &log))
+	assert.Equal(t, "u1", log["user_id"])
+	assert.Equal(t, "o1", log["order_id"])
+	assert.Equal(t, "not_found", log["error.code"])
+}
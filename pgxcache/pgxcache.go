@@ -0,0 +1,253 @@
+// Package pgxcache provides a two tier cache: an in memory cache.Cache in front of a Postgres table.
+// Writes notify all replicas via LISTEN/NOTIFY to evict their local copies.
+package pgxcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bir/iken/cache"
+)
+
+// ChannelSuffix is appended to the table name to build the NOTIFY channel.
+const ChannelSuffix = "_invalidate"
+
+// invalidation is the NOTIFY payload.  Source identifies the sending Cache, so it may ignore its own writes.
+type invalidation struct {
+	Source string `json:"s"`
+	Key    string `json:"k,omitempty"`
+	Clear  bool   `json:"c,omitempty"`
+}
+
+// Cache is a two tier cache.  Reads are served from the local cache, falling back to the Postgres table.
+// Set, Delete and Clear write to the table and NOTIFY all Cache instances sharing the table, which evict the key
+// from their local cache.  Values are stored as JSON.
+//
+// Listen must be running for invalidations from other replicas to be received.
+type Cache[V any] struct {
+	pool    *pgxpool.Pool
+	local   cache.Cache[string, V]
+	table   string
+	channel string
+	source  string
+
+	// generation is incremented on every invalidation, it guards against populating the local cache with a value
+	// read before a concurrent invalidation.  mu makes the generation check and the local write atomic.
+	mu         sync.Mutex
+	generation uint64
+}
+
+// New creates a two tier cache backed by `table`, using `local` as the in memory tier.
+func New[V any](pool *pgxpool.Pool, table string, local cache.Cache[string, V]) *Cache[V] {
+	return &Cache[V]{
+		pool:    pool,
+		local:   local,
+		table:   pgx.Identifier{table}.Sanitize(),
+		channel: table + ChannelSuffix,
+		source:  uuid.NewString(),
+	}
+}
+
+// CreateTable creates the backing table if it does not exist.
+func (c *Cache[V]) CreateTable(ctx context.Context) error {
+	_, err := c.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+c.table+` (
+	key        text PRIMARY KEY,
+	value      jsonb NOT NULL,
+	updated_at timestamptz NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		return fmt.Errorf("create table %s: %w", c.table, err)
+	}
+
+	return nil
+}
+
+// Get gets an item from the local cache, falling back to the table.
+// Returns the item or zero value, and a bool indicating whether the key was found.
+func (c *Cache[V]) Get(ctx context.Context, key string) (V, bool, error) { //nolint:ireturn // false positive
+	if v, found := c.local.Get(key); found {
+		return v, true, nil
+	}
+
+	var (
+		out  V
+		data []byte
+	)
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	err := c.pool.QueryRow(ctx, `SELECT value FROM `+c.table+` WHERE key = $1`, key).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return out, false, nil
+	}
+
+	if err != nil {
+		return out, false, fmt.Errorf("get %q: %w", key, err)
+	}
+
+	if err = json.Unmarshal(data, &out); err != nil {
+		return out, false, fmt.Errorf("decode %q: %w", key, err)
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.local.Set(key, out)
+	}
+	c.mu.Unlock()
+
+	return out, true, nil
+}
+
+// Set writes the item to the table and local cache, and notifies other instances to evict the key.
+func (c *Cache[V]) Set(ctx context.Context, key string, v V) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %q: %w", key, err)
+	}
+
+	// Concurrent Gets may have read the old row, invalidating discards their local writes.
+	c.invalidate(invalidation{Key: key})
+
+	err = c.notify(ctx, invalidation{Key: key}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO `+c.table+` (key, value) VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = now()`, key, data)
+
+		return err //nolint:wrapcheck // wrapped by notify
+	})
+	if err != nil {
+		return fmt.Errorf("set %q: %w", key, err)
+	}
+
+	// Gets that started before the commit may also have read the old row.
+	c.mu.Lock()
+	c.generation++
+	c.local.Set(key, v)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Delete deletes the item from the table and local cache, and notifies other instances to evict the key.
+func (c *Cache[V]) Delete(ctx context.Context, key string) error {
+	c.invalidate(invalidation{Key: key})
+
+	err := c.notify(ctx, invalidation{Key: key}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM `+c.table+` WHERE key = $1`, key)
+
+		return err //nolint:wrapcheck // wrapped by notify
+	})
+
+	// Gets that started before the commit may have read the deleted row.
+	c.invalidate(invalidation{Key: key})
+
+	if err != nil {
+		return fmt.Errorf("delete %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// Clear deletes all items from the table and local cache, and notifies other instances to clear.
+func (c *Cache[V]) Clear(ctx context.Context) error {
+	c.invalidate(invalidation{Clear: true})
+
+	err := c.notify(ctx, invalidation{Clear: true}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM `+c.table)
+
+		return err //nolint:wrapcheck // wrapped by notify
+	})
+
+	// Gets that started before the commit may have read deleted rows.
+	c.invalidate(invalidation{Clear: true})
+
+	if err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+
+	return nil
+}
+
+// Listen receives invalidations from other instances until the ctx is done or the connection fails.
+// This blocks, it is intended to be run in a separate go routine and restarted on error.
+// The local cache is cleared when listening starts, as invalidations may have been missed.
+func (c *Cache[V]) Listen(ctx context.Context) error {
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("listen acquire: %w", err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `LISTEN `+pgx.Identifier{c.channel}.Sanitize())
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	c.invalidate(invalidation{Clear: true})
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+
+		var msg invalidation
+
+		if err = json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+			// Unknown payload, fail safe.
+			msg = invalidation{Clear: true}
+		}
+
+		if msg.Source == c.source {
+			continue
+		}
+
+		c.invalidate(msg)
+	}
+}
+
+func (c *Cache[V]) invalidate(msg invalidation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	if msg.Clear {
+		c.local.Clear()
+	} else {
+		c.local.Delete(msg.Key)
+	}
+}
+
+// notify executes `fn` and the NOTIFY in a single transaction, the notification is only delivered on commit.
+func (c *Cache[V]) notify(ctx context.Context, msg invalidation, fn func(pgx.Tx) error) error {
+	msg.Source = c.source
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode notification: %w", err) // Ignore coverage - unlikely to error
+	}
+
+	err = pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, c.channel, string(payload))
+
+		return err //nolint:wrapcheck // wrapped by caller
+	})
+	if err != nil {
+		return fmt.Errorf("transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package pgxcache_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/cache"
+	"github.com/bir/iken/pgxcache"
+)
+
+// testPool connects to the database defined by PGX_TEST_DATABASE, skipping the test if it is not set.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	connString := os.Getenv("PGX_TEST_DATABASE")
+	if connString == "" {
+		t.Skip("PGX_TEST_DATABASE not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), connString)
+	require.NoError(t, err)
+
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestCache(t *testing.T) {
+	pool := testPool(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	const table = "pgxcache_test"
+
+	_, err := pool.Exec(ctx, `DROP TABLE IF EXISTS `+table)
+	require.NoError(t, err)
+
+	localA := cache.NewBasic[string, user]()
+	localB := cache.NewBasic[string, user]()
+	a := pgxcache.New[user](pool, table, localA)
+	b := pgxcache.New[user](pool, table, localB)
+
+	require.NoError(t, a.CreateTable(ctx))
+
+	listening := make(chan error, 2)
+
+	go func() { listening <- a.Listen(ctx) }()
+	go func() { listening <- b.Listen(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+		<-listening
+		<-listening
+	})
+
+	_, found, err := b.Get(ctx, "u1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, a.Set(ctx, "u1", user{"bob", 42}))
+
+	v, found, err := b.Get(ctx, "u1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, user{"bob", 42}, v)
+
+	_, found = localB.Get("u1")
+	assert.True(t, found, "populated local")
+
+	// Invalidation
+	require.NoError(t, a.Set(ctx, "u1", user{"bob", 43}))
+
+	assert.Eventually(t, func() bool {
+		_, found := localB.Get("u1")
+
+		return !found
+	}, 5*time.Second, 10*time.Millisecond)
+
+	v, _, err = b.Get(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, user{"bob", 43}, v)
+
+	// Own writes remain in the local cache.
+	_, found = localA.Get("u1")
+	assert.True(t, found)
+
+	require.NoError(t, a.Delete(ctx, "u1"))
+
+	assert.Eventually(t, func() bool {
+		_, found := localB.Get("u1")
+
+		return !found
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, found, err = b.Get(ctx, "u1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, b.Set(ctx, "u2", user{"alice", 30}))
+	_, _, err = a.Get(ctx, "u2")
+	require.NoError(t, err)
+
+	require.NoError(t, b.Clear(ctx))
+
+	assert.Eventually(t, func() bool {
+		return len(localA.Keys()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, found, err = a.Get(ctx, "u2")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestCacheConcurrentDelete(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	const table = "pgxcache_concurrent_test"
+
+	_, err := pool.Exec(ctx, `DROP TABLE IF EXISTS `+table)
+	require.NoError(t, err)
+
+	local := cache.NewBasic[string, user]()
+	c := pgxcache.New[user](pool, table, local)
+
+	require.NoError(t, c.CreateTable(ctx))
+
+	for range 50 {
+		require.NoError(t, c.Set(ctx, "u1", user{"bob", 42}))
+		local.Clear()
+
+		var wg sync.WaitGroup
+
+		for range 4 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, _, err := c.Get(ctx, "u1")
+				assert.NoError(t, err)
+			}()
+		}
+
+		require.NoError(t, c.Delete(ctx, "u1"))
+		wg.Wait()
+
+		_, found := local.Get("u1")
+		require.False(t, found, "Gets overlapping the Delete must not cache the deleted row")
+	}
+}
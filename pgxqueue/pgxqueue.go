@@ -0,0 +1,331 @@
+// Package pgxqueue provides a durable job queue in a Postgres table, feeding a worker.FanOut.
+// Jobs are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so any number of instances may share the table.
+package pgxqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/bir/iken/worker"
+)
+
+// ErrPanic is recorded as the last_error of jobs whose handler panicked.
+var ErrPanic = errors.New("job panic")
+
+// ErrAbandoned is recorded as the last_error of jobs whose last attempt was not completed or failed within the
+// visibility timeout.
+var ErrAbandoned = errors.New("job abandoned")
+
+// Defaults used by New.
+const (
+	DefaultVisibilityTimeout = 5 * time.Minute
+	DefaultPollInterval      = time.Second
+	DefaultBatchSize         = 10
+)
+
+// DefaultRetryPolicy is used by New.  Only MaxAttempts and the backoff fields are used, RetryIf is ignored.
+var DefaultRetryPolicy = worker.RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Hour,
+	Jitter:         0.2,
+}
+
+// Execer is implemented by *pgxpool.Pool, *pgx.Conn and pgx.Tx.  Enqueue with a pgx.Tx to make the job
+// transactional with other writes.
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// Job is a claimed job.  Attempts includes the current attempt.
+type Job[T any] struct {
+	ID       int64
+	Payload  T
+	Attempts int
+	RunAt    time.Time
+}
+
+// Queue is a durable job queue stored in a table, with payloads stored as JSON.
+//
+// Claimed jobs are invisible to other consumers for the visibility timeout.  Jobs that are not completed or failed in
+// time, for example after a crash, are claimed again, or marked failed with ErrAbandoned if that was the last attempt.
+// Failed jobs are retried with backoff until the retry policy MaxAttempts, then kept in the table with failed_at set.
+type Queue[T any] struct {
+	pool              *pgxpool.Pool
+	table             string
+	visibilityTimeout time.Duration
+	pollInterval      time.Duration
+	batchSize         int
+	retry             worker.RetryPolicy
+}
+
+// New creates a queue backed by `table`.
+func New[T any](pool *pgxpool.Pool, table string) *Queue[T] {
+	return &Queue[T]{
+		pool:              pool,
+		table:             pgx.Identifier{table}.Sanitize(),
+		visibilityTimeout: DefaultVisibilityTimeout,
+		pollInterval:      DefaultPollInterval,
+		batchSize:         DefaultBatchSize,
+		retry:             DefaultRetryPolicy,
+	}
+}
+
+// WithVisibilityTimeout sets how long a claimed job is hidden from other consumers.  It must exceed the longest
+// expected processing time.
+func (q *Queue[T]) WithVisibilityTimeout(d time.Duration) *Queue[T] {
+	q.visibilityTimeout = d
+
+	return q
+}
+
+// WithPollInterval sets the delay between claims when the queue is empty.
+func (q *Queue[T]) WithPollInterval(d time.Duration) *Queue[T] {
+	q.pollInterval = d
+
+	return q
+}
+
+// WithBatchSize sets the maximum jobs claimed per query.  Claimed jobs wait for a free worker, keep this close to the
+// worker count so they are not held past the visibility timeout.
+func (q *Queue[T]) WithBatchSize(n int) *Queue[T] {
+	q.batchSize = max(n, 1)
+
+	return q
+}
+
+// WithRetryPolicy sets the attempts and backoff for failed jobs.
+func (q *Queue[T]) WithRetryPolicy(p worker.RetryPolicy) *Queue[T] {
+	q.retry = p
+
+	return q
+}
+
+// CreateTable creates the backing table if it does not exist.
+func (q *Queue[T]) CreateTable(ctx context.Context) error {
+	_, err := q.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+q.table+` (
+	id           bigserial PRIMARY KEY,
+	payload      jsonb NOT NULL,
+	attempts     int NOT NULL DEFAULT 0,
+	run_at       timestamptz NOT NULL DEFAULT now(),
+	locked_until timestamptz,
+	last_error   text,
+	failed_at    timestamptz,
+	created_at   timestamptz NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		return fmt.Errorf("create table %s: %w", q.table, err)
+	}
+
+	return nil
+}
+
+// Enqueue adds a job to run immediately.  `db` may be a pgx.Tx, the job is only visible once committed.
+func (q *Queue[T]) Enqueue(ctx context.Context, db Execer, payload T) error {
+	return q.EnqueueAt(ctx, db, payload, time.Time{})
+}
+
+// EnqueueAt adds a job to run at `runAt`, a zero time runs immediately.  `db` may be a pgx.Tx.
+func (q *Queue[T]) EnqueueAt(ctx context.Context, db Execer, payload T, runAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode job: %w", err)
+	}
+
+	var at *time.Time
+	if !runAt.IsZero() {
+		at = &runAt
+	}
+
+	_, err = db.Exec(ctx, `INSERT INTO `+q.table+` (payload, run_at) VALUES ($1, coalesce($2, now()))`, data, at)
+	if err != nil {
+		return fmt.Errorf("enqueue: %w", err)
+	}
+
+	return nil
+}
+
+// Claim claims up to `limit` due jobs, hiding them from other consumers for the visibility timeout.  Jobs out of
+// attempts are not claimed, those whose visibility timeout expired are marked failed first.
+//
+// Jobs with payloads that can not be decoded are marked failed, they are reported in the error along with the jobs
+// that were claimed.
+func (q *Queue[T]) Claim(ctx context.Context, limit int) ([]Job[T], error) {
+	_, err := q.pool.Exec(ctx, `UPDATE `+q.table+`
+SET failed_at = now(), locked_until = NULL, last_error = $2
+WHERE failed_at IS NULL AND attempts >= $1 AND locked_until <= now()`, q.maxAttempts(), ErrAbandoned.Error())
+	if err != nil {
+		return nil, fmt.Errorf("claim abandoned: %w", err)
+	}
+
+	rows, err := q.pool.Query(ctx, `UPDATE `+q.table+`
+SET attempts = attempts + 1, locked_until = now() + $1::interval
+WHERE id IN (
+	SELECT id FROM `+q.table+`
+	WHERE failed_at IS NULL AND attempts < $3 AND run_at <= now() AND (locked_until IS NULL OR locked_until <= now())
+	ORDER BY run_at, id
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, payload, attempts, run_at`, q.visibilityTimeout, limit, q.maxAttempts())
+	if err != nil {
+		return nil, fmt.Errorf("claim: %w", err)
+	}
+
+	type claimed struct {
+		job  Job[T]
+		data []byte
+	}
+
+	claims, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (claimed, error) {
+		var c claimed
+
+		err := row.Scan(&c.job.ID, &c.data, &c.job.Attempts, &c.job.RunAt)
+
+		return c, err //nolint:wrapcheck // wrapped below
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim: %w", err)
+	}
+
+	var (
+		jobs = make([]Job[T], 0, len(claims))
+		errs []error
+	)
+
+	for _, c := range claims {
+		if err := json.Unmarshal(c.data, &c.job.Payload); err != nil {
+			err = fmt.Errorf("decode job %d: %w", c.job.ID, err)
+			errs = append(errs, err, q.markFailed(ctx, c.job, err))
+
+			continue
+		}
+
+		jobs = append(jobs, c.job)
+	}
+
+	return jobs, errors.Join(errs...)
+}
+
+// Complete deletes the job.  It is a no-op if the claim expired and the job was claimed again.
+func (q *Queue[T]) Complete(ctx context.Context, job Job[T]) error {
+	_, err := q.pool.Exec(ctx, `DELETE FROM `+q.table+` WHERE id = $1 AND attempts = $2`, job.ID, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("complete job %d: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// Fail releases the job to be retried after the retry policy backoff, or marks it failed once out of attempts.
+// It is a no-op if the claim expired and the job was claimed again.
+func (q *Queue[T]) Fail(ctx context.Context, job Job[T], jobErr error) error {
+	if job.Attempts >= q.maxAttempts() {
+		return q.markFailed(ctx, job, jobErr)
+	}
+
+	_, err := q.pool.Exec(ctx, `UPDATE `+q.table+`
+SET run_at = now() + $3::interval, locked_until = NULL, last_error = $4
+WHERE id = $1 AND attempts = $2`, job.ID, job.Attempts, q.retry.Backoff(job.Attempts), jobErr.Error())
+	if err != nil {
+		return fmt.Errorf("fail job %d: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// markFailed keeps the job in the table with failed_at set, it is not retried.
+func (q *Queue[T]) markFailed(ctx context.Context, job Job[T], jobErr error) error {
+	_, err := q.pool.Exec(ctx, `UPDATE `+q.table+`
+SET failed_at = now(), locked_until = NULL, last_error = $3
+WHERE id = $1 AND attempts = $2`, job.ID, job.Attempts, jobErr.Error())
+	if err != nil {
+		return fmt.Errorf("fail job %d: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+func (q *Queue[T]) maxAttempts() int {
+	return max(q.retry.MaxAttempts, 1)
+}
+
+// Run claims jobs and feeds them to `pool` until the ctx is done, completing jobs when `handler` succeeds and failing
+// them otherwise.  This blocks, it is intended to be run in a separate go routine.
+//
+// Handler panics fail the job, and are reported by the pool, see worker.FanOut.WithPanicFunc.
+//
+// When the ctx is done claiming stops, the pool is closed and Run returns once already claimed jobs are handled.
+// Handlers receive a ctx that is not cancelled, so in-flight jobs may finish.  Jobs interrupted by a crash are
+// claimed again after the visibility timeout.
+// Database errors are logged to the ctx logger.
+func (q *Queue[T]) Run(ctx context.Context, pool *worker.FanOut[Job[T]],
+	handler worker.ContextProcessorFunc[Job[T]],
+) error {
+	go q.poll(ctx, pool)
+
+	process := func(ctx context.Context, job Job[T]) error {
+		defer func() {
+			if r := recover(); r != nil {
+				if err := q.Fail(ctx, job, fmt.Errorf("%w: %v", ErrPanic, r)); err != nil {
+					zerolog.Ctx(ctx).Error().Err(err).Msg("job queue")
+				}
+
+				panic(r) // reported by the pool
+			}
+		}()
+
+		if err := handler(ctx, job); err != nil {
+			if err := q.Fail(ctx, job, err); err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Msg("job queue")
+			}
+
+			return nil
+		}
+
+		if err := q.Complete(ctx, job); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("job queue")
+		}
+
+		return nil
+	}
+
+	return pool.ProcessContext(context.WithoutCancel(ctx), process) //nolint:wrapcheck // only panics are returned
+}
+
+// poll claims jobs into the pool until the ctx is done, then closes the pool.
+func (q *Queue[T]) poll(ctx context.Context, pool *worker.FanOut[Job[T]]) {
+	defer pool.Close()
+
+	for {
+		jobs, err := q.Claim(ctx, q.batchSize)
+		if err != nil && ctx.Err() == nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("job queue")
+		}
+
+		for _, job := range jobs {
+			// Claimed jobs are handed off even when the ctx is done, they are processed before Run returns.
+			if err := pool.Invoke(job); err != nil {
+				return
+			}
+		}
+
+		if len(jobs) == q.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.pollInterval):
+		}
+	}
+}
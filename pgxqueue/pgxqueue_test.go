@@ -0,0 +1,305 @@
+package pgxqueue_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/pgxqueue"
+	"github.com/bir/iken/worker"
+)
+
+// testQueue connects to the database defined by PGX_TEST_DATABASE, skipping the test if it is not set.
+func testQueue(t *testing.T) (*pgxpool.Pool, *pgxqueue.Queue[task]) {
+	t.Helper()
+
+	connString := os.Getenv("PGX_TEST_DATABASE")
+	if connString == "" {
+		t.Skip("PGX_TEST_DATABASE not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), connString)
+	require.NoError(t, err)
+
+	t.Cleanup(pool.Close)
+
+	const table = "pgxqueue_test"
+
+	_, err = pool.Exec(context.Background(), `DROP TABLE IF EXISTS `+table)
+	require.NoError(t, err)
+
+	q := pgxqueue.New[task](pool, table).
+		WithPollInterval(10 * time.Millisecond).
+		WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour})
+	require.NoError(t, q.CreateTable(context.Background()))
+
+	return pool, q
+}
+
+type task struct {
+	Name string `json:"name"`
+}
+
+var errTask = errors.New("task failed")
+
+func TestEnqueueTx(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx := context.Background()
+
+	err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		require.NoError(t, q.Enqueue(ctx, tx, task{"rolled back"}))
+
+		return errTask
+	})
+	require.ErrorIs(t, err, errTask)
+
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		return q.Enqueue(ctx, tx, task{"committed"})
+	})
+	require.NoError(t, err)
+
+	jobs, err := q.Claim(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "committed", jobs[0].Payload.Name)
+	assert.Equal(t, 1, jobs[0].Attempts)
+}
+
+func TestClaim(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Enqueue(ctx, pool, task{name}))
+	}
+
+	require.NoError(t, q.EnqueueAt(ctx, pool, task{"later"}, time.Now().Add(time.Hour)))
+
+	first, err := q.Claim(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	second, err := q.Claim(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, second, 1, "claimed and future jobs are not visible")
+	assert.Equal(t, "c", second[0].Payload.Name)
+
+	// Complete removes the job, a stale claim is ignored.
+	require.NoError(t, q.Complete(ctx, first[0]))
+
+	stale := first[1]
+	stale.Attempts = 99
+	require.NoError(t, q.Complete(ctx, stale))
+
+	var n int
+
+	require.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM pgxqueue_test`).Scan(&n))
+	assert.Equal(t, 3, n)
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx := context.Background()
+
+	q.WithVisibilityTimeout(10 * time.Millisecond)
+
+	require.NoError(t, q.Enqueue(ctx, pool, task{"a"}))
+
+	jobs, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	time.Sleep(20 * time.Millisecond)
+
+	again, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, again, 1, "claimed again after the visibility timeout")
+	assert.Equal(t, 2, again[0].Attempts)
+
+	time.Sleep(20 * time.Millisecond)
+
+	again, err = q.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, again, "out of attempts")
+}
+
+func TestClaimAbandoned(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx := context.Background()
+
+	q.WithVisibilityTimeout(10 * time.Millisecond)
+
+	require.NoError(t, q.Enqueue(ctx, pool, task{"a"}))
+
+	// Both attempts expire without completing, as after a crash.
+	for range 2 {
+		jobs, err := q.Claim(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	jobs, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	var (
+		failed    bool
+		lastError string
+	)
+
+	require.NoError(t, pool.QueryRow(ctx, `SELECT failed_at IS NOT NULL, last_error FROM pgxqueue_test`).
+		Scan(&failed, &lastError))
+	assert.True(t, failed, "abandoned on the last attempt")
+	assert.Equal(t, pgxqueue.ErrAbandoned.Error(), lastError)
+}
+
+func TestFail(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx := context.Background()
+
+	require.NoError(t, q.Enqueue(ctx, pool, task{"a"}))
+
+	jobs, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, q.Fail(ctx, jobs[0], errTask))
+
+	var (
+		runAt     time.Time
+		lastError string
+	)
+
+	require.NoError(t, pool.QueryRow(ctx, `SELECT run_at, last_error FROM pgxqueue_test`).Scan(&runAt, &lastError))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), runAt, 10*time.Minute, "retry after backoff")
+	assert.Equal(t, errTask.Error(), lastError)
+
+	// Out of attempts
+	_, err = pool.Exec(ctx, `UPDATE pgxqueue_test SET run_at = now()`)
+	require.NoError(t, err)
+
+	jobs, err = q.Claim(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.NoError(t, q.Fail(ctx, jobs[0], errTask))
+
+	var failed bool
+
+	require.NoError(t, pool.QueryRow(ctx, `SELECT failed_at IS NOT NULL FROM pgxqueue_test`).Scan(&failed))
+	assert.True(t, failed)
+
+	jobs, err = q.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestRun(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	for _, name := range []string{"a", "b", "fail", "c"} {
+		require.NoError(t, q.Enqueue(ctx, pool, task{name}))
+	}
+
+	var (
+		m    sync.Mutex
+		seen []string
+	)
+
+	done := make(chan error)
+
+	go func() {
+		done <- q.Run(ctx, worker.NewFanOut[pgxqueue.Job[task]](2, 0),
+			func(_ context.Context, job pgxqueue.Job[task]) error {
+				m.Lock()
+				defer m.Unlock()
+
+				seen = append(seen, job.Payload.Name)
+
+				if job.Payload.Name == "fail" {
+					return errTask
+				}
+
+				return nil
+			})
+	}()
+
+	assert.Eventually(t, func() bool {
+		m.Lock()
+		defer m.Unlock()
+
+		return len(seen) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	rows, err := pool.Query(context.Background(), `SELECT payload->>'name' FROM pgxqueue_test`)
+	require.NoError(t, err)
+
+	remaining, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fail"}, remaining, "completed jobs deleted, failed job retained for retry")
+}
+
+func TestClaimBadPayload(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx := context.Background()
+
+	require.NoError(t, q.Enqueue(ctx, pool, task{"a"}))
+
+	_, err := pool.Exec(ctx, `INSERT INTO pgxqueue_test (payload) VALUES ('{"name": 1}')`)
+	require.NoError(t, err)
+
+	require.NoError(t, q.Enqueue(ctx, pool, task{"b"}))
+
+	jobs, err := q.Claim(ctx, 10)
+	require.Error(t, err)
+	require.Len(t, jobs, 2, "decoded jobs are returned")
+	assert.Equal(t, "a", jobs[0].Payload.Name)
+	assert.Equal(t, "b", jobs[1].Payload.Name)
+
+	var failed int
+
+	require.NoError(t, pool.QueryRow(ctx, `SELECT count(*) FROM pgxqueue_test WHERE failed_at IS NOT NULL`).
+		Scan(&failed))
+	assert.Equal(t, 1, failed)
+}
+
+func TestRunPanic(t *testing.T) {
+	pool, q := testQueue(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, q.Enqueue(ctx, pool, task{"panic"}))
+
+	var panics atomic.Int32
+
+	done := make(chan error)
+
+	go func() {
+		done <- q.Run(ctx, worker.NewFanOut[pgxqueue.Job[task]](1, 0).
+			WithPanicFunc(func(context.Context, pgxqueue.Job[task], *worker.PanicError) { panics.Add(1) }),
+			func(context.Context, pgxqueue.Job[task]) error {
+				panic("boom")
+			})
+	}()
+
+	assert.Eventually(t, func() bool { return panics.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	var lastError string
+
+	require.NoError(t, pool.QueryRow(context.Background(), `SELECT last_error FROM pgxqueue_test`).Scan(&lastError))
+	assert.Equal(t, "job panic: boom", lastError)
+}
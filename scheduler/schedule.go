@@ -0,0 +1,259 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSchedule is returned by ParseCron for malformed expressions, and when adding a non-positive interval.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule returns the next activation time after the given time, or the zero time if there is none.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Every returns a fixed interval schedule.  Each activation is delayed by a random duration in [0, jitter), to spread
+// load across instances.  A non-positive interval never activates, Scheduler.Add rejects it.
+func Every(interval, jitter time.Duration) Schedule {
+	return every{interval: interval, jitter: jitter}
+}
+
+type every struct {
+	interval time.Duration
+	jitter   time.Duration
+}
+
+func (e every) Next(after time.Time) time.Time {
+	if e.interval <= 0 {
+		return time.Time{}
+	}
+
+	next := after.Add(e.interval)
+
+	if e.jitter > 0 {
+		next = next.Add(rand.N(e.jitter)) //nolint:gosec // jitter does not need crypto
+	}
+
+	return next
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// ParseCron parses a standard 5 field cron expression (minute hour day-of-month month day-of-week), or one of the
+// descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly and "@every <duration>".
+//
+// Fields support *, lists (1,2), ranges (1-5), steps (*/15, 1-30/5) and month and day names (JAN, MON).
+// Day of week 0 and 7 are Sunday.  As with standard cron, when both day of month and day of week are restricted a
+// day matching either runs.  Times are evaluated in the location of the time passed to Next, times skipped by a
+// daylight saving transition do not run and times repeated by one may run twice.
+func ParseCron(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSchedule, expr)
+		}
+
+		return Every(d, 0), nil
+	}
+
+	if d, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 { //nolint:mnd // cron fields
+		return nil, fmt.Errorf("%w: %q expected 5 fields", ErrInvalidSchedule, expr)
+	}
+
+	var (
+		c   cron
+		err error
+	)
+
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+
+	if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+
+	if c.dow.has(7) {
+		c.dow |= 1 // Sunday
+	}
+
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+
+	return c, nil
+}
+
+// bits is a set of field values.
+type bits uint64
+
+func (b bits) has(v int) bool {
+	return b&(1<<uint(v)) != 0
+}
+
+func parseField(field string, low, high int, names map[string]int) (bits, error) {
+	var out bits
+
+	for _, part := range strings.Split(field, ",") {
+		b, err := parseRange(part, low, high, names)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q in %q", ErrInvalidSchedule, part, field)
+		}
+
+		out |= b
+	}
+
+	return out, nil
+}
+
+func parseRange(part string, low, high int, names map[string]int) (bits, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	step := 1
+
+	if hasStep {
+		var err error
+
+		step, err = strconv.Atoi(stepPart)
+		if err != nil || step <= 0 {
+			return 0, ErrInvalidSchedule
+		}
+	}
+
+	start, end := low, high
+
+	if rangePart != "*" {
+		from, to, isRange := strings.Cut(rangePart, "-")
+
+		var err error
+
+		if start, err = parseValue(from, names); err != nil {
+			return 0, err
+		}
+
+		switch {
+		case isRange:
+			if end, err = parseValue(to, names); err != nil {
+				return 0, err
+			}
+		case !hasStep:
+			end = start
+		}
+	}
+
+	if start < low || end > high || start > end {
+		return 0, ErrInvalidSchedule
+	}
+
+	var out bits
+	for v := start; v <= end; v += step {
+		out |= 1 << uint(v)
+	}
+
+	return out, nil
+}
+
+func parseValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, ErrInvalidSchedule
+	}
+
+	return v, nil
+}
+
+type cron struct {
+	minute, hour, dom, month, dow bits
+	domAny, dowAny                bool
+}
+
+// maxSearch bounds the search for impossible schedules, such as February 30th.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+func (c cron) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+
+		switch {
+		case !c.month.has(int(month)):
+			t = later(t, time.Date(year, month+1, 1, 0, 0, 0, 0, loc))
+		case !c.dayMatches(t):
+			t = later(t, time.Date(year, month, day+1, 0, 0, 0, 0, loc))
+		case !c.hour.has(t.Hour()):
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute) //nolint:mnd // minutes per hour
+		case !c.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// later returns next, or an hour after t when a daylight saving transition normalizes next to before t.
+func later(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+
+	return t.Add(time.Hour)
+}
+
+func (c cron) dayMatches(t time.Time) bool {
+	dom := c.dom.has(t.Day())
+	dow := c.dow.has(int(t.Weekday()))
+
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
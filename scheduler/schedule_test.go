@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"every minute", "* * * * *", at(2024, 1, 1, 10, 30).Add(15 * time.Second), at(2024, 1, 1, 10, 31)},
+		{"minute exact", "30 * * * *", at(2024, 1, 1, 10, 30), at(2024, 1, 1, 11, 30)},
+		{"step", "*/15 * * * *", at(2024, 1, 1, 10, 31), at(2024, 1, 1, 10, 45)},
+		{"range step", "10-40/10 * * * *", at(2024, 1, 1, 10, 41), at(2024, 1, 1, 11, 10)},
+		{"start step", "5/20 * * * *", at(2024, 1, 1, 10, 26), at(2024, 1, 1, 10, 45)},
+		{"list", "0 6,18 * * *", at(2024, 1, 1, 7, 0), at(2024, 1, 1, 18, 0)},
+		{"daily", "@daily", at(2024, 1, 31, 23, 59), at(2024, 2, 1, 0, 0)},
+		{"hourly", "@HOURLY", at(2024, 1, 1, 10, 0), at(2024, 1, 1, 11, 0)},
+		{"yearly", "@yearly", at(2024, 3, 1, 0, 0), at(2025, 1, 1, 0, 0)},
+		{"month names", "0 0 1 mar,JUN *", at(2024, 3, 1, 0, 0), at(2024, 6, 1, 0, 0)},
+		{"day names", "0 9 * * mon-fri", at(2024, 1, 5, 9, 0), at(2024, 1, 8, 9, 0)},
+		{"sunday as 7", "0 0 * * 7", at(2024, 1, 1, 0, 0), at(2024, 1, 7, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2024, 3, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"dom or dow", "0 0 15 * fri", at(2024, 1, 6, 0, 0), at(2024, 1, 12, 0, 0)},
+		{"dom or dow dom first", "0 0 15 * fri", at(2024, 1, 13, 0, 0), at(2024, 1, 15, 0, 0)},
+		{"dom and any dow", "0 0 15 * *", at(2024, 1, 6, 0, 0), at(2024, 1, 15, 0, 0)},
+		{"impossible", "0 0 30 2 *", at(2024, 1, 1, 0, 0), time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseCron(test.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", test.expr, err)
+			}
+
+			if got := s.Next(test.after); !got.Equal(test.want) {
+				t.Errorf("Next(%v) = %v, want %v", test.after, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCronLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	s, err := ParseCron("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 02:30 does not exist on the spring forward day, Next moves to the following valid time.
+	got := s.Next(time.Date(2024, 3, 9, 3, 0, 0, 0, loc))
+	if !got.After(time.Date(2024, 3, 10, 0, 0, 0, 0, loc)) || got.After(time.Date(2024, 3, 11, 2, 30, 0, 0, loc)) {
+		t.Errorf("Next = %v", got)
+	}
+
+	got = s.Next(time.Date(2024, 6, 1, 3, 0, 0, 0, loc))
+	if want := time.Date(2024, 6, 2, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestParseCronEvery(t *testing.T) {
+	s, err := ParseCron("@every 90s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := s.Next(now); !got.Equal(now.Add(90 * time.Second)) {
+		t.Errorf("Next = %v", got)
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"1,,2 * * * *",
+		"@every",
+		"@every 0s",
+		"@every bad",
+		"@reboot",
+	} {
+		if _, err := ParseCron(expr); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("ParseCron(%q) error = %v, want ErrInvalidSchedule", expr, err)
+		}
+	}
+}
+
+func TestEvery(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := Every(time.Minute, 0).Next(now); !got.Equal(now.Add(time.Minute)) {
+		t.Errorf("Next = %v", got)
+	}
+
+	s := Every(time.Minute, 10*time.Second)
+
+	for range 100 {
+		got := s.Next(now).Sub(now)
+		if got < time.Minute || got >= time.Minute+10*time.Second {
+			t.Fatalf("Next = %v, want [1m, 1m10s)", got)
+		}
+	}
+
+	if got := Every(0, time.Second).Next(now); !got.IsZero() {
+		t.Errorf("Next zero interval = %v, want zero", got)
+	}
+}
@@ -0,0 +1,235 @@
+// Package scheduler runs periodic jobs in process, on cron expressions or fixed intervals.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/bir/iken/httplog"
+	"github.com/bir/iken/logctx"
+)
+
+// Log field names added to job loggers.
+const (
+	LogJob         = "job.name"
+	LogJobDuration = "job.duration"
+)
+
+// ErrDuplicateJob is returned when adding a job with a name already in use.
+var ErrDuplicateJob = errors.New("duplicate job")
+
+// nowFunc and newTimer are utilities used for automated testing (overriding the runtime clock).
+var (
+	nowFunc  = time.Now
+	newTimer = func(d time.Duration) (<-chan time.Time, func() bool) {
+		t := time.NewTimer(d)
+
+		return t.C, t.Stop
+	}
+)
+
+// stackSkip defines the lines to skip in the panic stack logger - this is determined by the structure of this code.
+const stackSkip = 3
+
+// Job is a scheduled function.  The ctx carries a zerolog sub-logger with the job name, which the job may enrich
+// with the logctx helpers, and is cancelled if Stop times out.
+type Job func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	schedule Schedule
+	job      Job
+	running  atomic.Bool
+}
+
+// Scheduler runs jobs on their schedules.  A job is never run concurrently with itself, activations while the
+// previous run is in progress are skipped.  Panics are recovered and logged.
+type Scheduler struct {
+	log zerolog.Logger
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	ctx     context.Context //nolint:containedctx // scheduling ctx, set by Start
+	cancel  context.CancelFunc
+	jobs    context.Context //nolint:containedctx // ctx for running jobs, cancelled if Stop times out
+	stopJob context.CancelFunc
+	stopped bool
+	loops   sync.WaitGroup
+	runs    sync.WaitGroup
+}
+
+// New creates a scheduler, logging to `log`.
+func New(log zerolog.Logger) *Scheduler {
+	return &Scheduler{log: log, entries: make(map[string]*entry)}
+}
+
+// Add schedules the job.  Jobs added after Start are scheduled immediately, jobs added after Stop are not scheduled.  Returns ErrInvalidSchedule for an Every
+// schedule with a non-positive interval.
+func (s *Scheduler) Add(name string, schedule Schedule, job Job) error {
+	if e, ok := schedule.(every); ok && e.interval <= 0 {
+		return fmt.Errorf("%w: interval %v", ErrInvalidSchedule, e.interval)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateJob, name)
+	}
+
+	e := &entry{name: name, schedule: schedule, job: job}
+	s.entries[name] = e
+
+	if s.ctx != nil && !s.stopped {
+		s.startLoop(e)
+	}
+
+	return nil
+}
+
+// AddCron schedules the job with a cron expression, see ParseCron.
+func (s *Scheduler) AddCron(name, expr string, job Job) error {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return err
+	}
+
+	return s.Add(name, schedule, job)
+}
+
+// AddInterval schedules the job every `interval` plus a random jitter, see Every.  Returns ErrInvalidSchedule if the
+// interval is not positive.
+func (s *Scheduler) AddInterval(name string, interval, jitter time.Duration, job Job) error {
+	return s.Add(name, Every(interval, jitter), job)
+}
+
+// Start starts scheduling until the ctx is done or Stop is called.  Running jobs are not cancelled by the ctx, see
+// Stop.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil {
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.jobs, s.stopJob = context.WithCancel(context.WithoutCancel(ctx))
+
+	for _, e := range s.entries {
+		s.startLoop(e)
+	}
+}
+
+// Stop stops scheduling and waits for running jobs to finish.  If the ctx is done first, running jobs are cancelled
+// and the ctx error is returned without waiting for them.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, stopJobs := s.cancel, s.stopJob
+	// No loops are started once stopped, so waiting for them is safe.
+	s.stopped = cancel != nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	s.loops.Wait()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		stopJobs()
+
+		return nil
+	case <-ctx.Done():
+		stopJobs()
+
+		return ctx.Err()
+	}
+}
+
+// startLoop must be called with s.mu held.
+func (s *Scheduler) startLoop(e *entry) {
+	s.loops.Add(1)
+
+	go func() {
+		defer s.loops.Done()
+
+		s.loop(e)
+	}()
+}
+
+func (s *Scheduler) loop(e *entry) {
+	for {
+		now := nowFunc()
+
+		next := e.schedule.Next(now)
+		if next.IsZero() {
+			return
+		}
+
+		c, stop := newTimer(next.Sub(now))
+
+		select {
+		case <-s.ctx.Done():
+			stop()
+
+			return
+		case <-c:
+		}
+
+		if !e.running.CompareAndSwap(false, true) {
+			s.log.Warn().Str(LogJob, e.name).Msg("job skipped, still running")
+
+			continue
+		}
+
+		s.runs.Add(1)
+
+		go func() {
+			defer s.runs.Done()
+			defer e.running.Store(false)
+
+			s.run(e)
+		}()
+	}
+}
+
+func (s *Scheduler) run(e *entry) {
+	l := s.log.With().Str(LogJob, e.name).Logger()
+	ctx := logctx.SetOperation(l.WithContext(s.jobs), e.name)
+	start := nowFunc()
+
+	defer func() {
+		if r := recover(); r != nil {
+			httplog.LogRecoverError(ctx, stackSkip, r)
+		}
+	}()
+
+	err := e.job(ctx)
+
+	log := zerolog.Ctx(ctx)
+	duration := nowFunc().Sub(start)
+
+	if err != nil {
+		log.Error().Err(err).Dur(LogJobDuration, duration).Msg("job failed")
+
+		return
+	}
+
+	log.Debug().Dur(LogJobDuration, duration).Msg("job done")
+}
@@ -0,0 +1,387 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/bir/iken/logctx"
+)
+
+// fakeClock replaces nowFunc and newTimer, timers fire when the clock is advanced past them.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+
+	c := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	oldNow, oldTimer := nowFunc, newTimer
+	nowFunc, newTimer = c.Now, c.NewTimer
+
+	t.Cleanup(func() { nowFunc, newTimer = oldNow, oldTimer })
+
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+
+	return t.c, func() bool { return c.remove(t) }
+}
+
+func (c *fakeClock) remove(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, ft := range c.timers {
+		if ft == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Advance moves the clock forward, firing the timers due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := c.timers[:0]
+
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+
+			continue
+		}
+
+		t.c <- c.now
+	}
+
+	c.timers = pending
+}
+
+// WaitTimers waits until n timers are pending.
+func (c *fakeClock) WaitTimers(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		got := len(c.timers)
+		c.mu.Unlock()
+
+		if got == n {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %d timers", n)
+}
+
+// syncBuffer is a goroutine safe log writer.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.String()
+}
+
+func TestScheduler(t *testing.T) {
+	clock := newFakeClock(t)
+
+	var (
+		out  syncBuffer
+		runs atomic.Int32
+	)
+
+	s := New(zerolog.New(&out))
+
+	ran := make(chan string, 10)
+
+	err := s.AddInterval("tick", time.Minute, 0, func(ctx context.Context) error {
+		runs.Add(1)
+		ran <- logctx.GetOperation(ctx)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AddInterval("tick", time.Minute, 0, nil); !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("Add duplicate error = %v", err)
+	}
+
+	if err := s.AddCron("bad", "bad", nil); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("AddCron error = %v", err)
+	}
+
+	if err := s.AddInterval("zero", 0, 0, nil); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("AddInterval error = %v", err)
+	}
+
+	s.Start(context.Background())
+	clock.WaitTimers(t, 1)
+
+	clock.Advance(30 * time.Second)
+
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("runs = %d before due", got)
+	}
+
+	clock.Advance(30 * time.Second)
+
+	if op := <-ran; op != "tick" {
+		t.Errorf("operation = %q", op)
+	}
+
+	clock.WaitTimers(t, 1)
+	clock.Advance(time.Minute)
+	<-ran
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.WaitTimers(t, 0)
+
+	if got := runs.Load(); got != 2 {
+		t.Errorf("runs = %d, want 2", got)
+	}
+}
+
+func TestSchedulerAddAfterStart(t *testing.T) {
+	clock := newFakeClock(t)
+	s := New(zerolog.Nop())
+	s.Start(context.Background())
+
+	ran := make(chan struct{}, 1)
+
+	err := s.AddCron("hourly", "@hourly", func(context.Context) error {
+		ran <- struct{}{}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.WaitTimers(t, 1)
+	clock.Advance(time.Hour)
+	<-ran
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerAddDuringStop(t *testing.T) {
+	clock := newFakeClock(t)
+	s := New(zerolog.Nop())
+	s.Start(context.Background())
+
+	var wg sync.WaitGroup
+
+	for i := range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_ = s.AddInterval(strconv.Itoa(i), time.Minute, 0, func(context.Context) error { return nil })
+		}()
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+
+	if err := s.AddInterval("late", time.Minute, 0, func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.WaitTimers(t, 0)
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	clock := newFakeClock(t)
+
+	var (
+		out  syncBuffer
+		runs atomic.Int32
+	)
+
+	s := New(zerolog.New(&out))
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	err := s.AddInterval("slow", time.Minute, 0, func(context.Context) error {
+		runs.Add(1)
+		started <- struct{}{}
+		<-release
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Start(context.Background())
+	clock.WaitTimers(t, 1)
+	clock.Advance(time.Minute)
+	<-started
+
+	clock.WaitTimers(t, 1)
+	clock.Advance(time.Minute)
+	clock.WaitTimers(t, 1)
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("runs = %d, want 1", got)
+	}
+
+	if !strings.Contains(out.String(), "job skipped, still running") {
+		t.Errorf("missing skip log: %s", out.String())
+	}
+
+	close(release)
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerPanic(t *testing.T) {
+	clock := newFakeClock(t)
+
+	var out syncBuffer
+
+	s := New(zerolog.New(&out))
+	errJob := errors.New("job error")
+	calls := make(chan struct{}, 2)
+
+	_ = s.AddInterval("panic", time.Minute, 0, func(ctx context.Context) error {
+		logctx.AddStrToContext(ctx, "custom", "value")
+		calls <- struct{}{}
+
+		panic("boom")
+	})
+	_ = s.AddInterval("fail", time.Minute, 0, func(context.Context) error {
+		calls <- struct{}{}
+
+		return errJob
+	})
+
+	s.Start(context.Background())
+	clock.WaitTimers(t, 2)
+	clock.Advance(time.Minute)
+	<-calls
+	<-calls
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	logs := out.String()
+
+	for _, want := range []string{
+		`"job.name":"panic"`, `"custom":"value"`, `"message":"Panic"`, `boom`,
+		`"job.name":"fail"`, `"error":"job error"`, `"message":"job failed"`,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("missing %s in logs: %s", want, logs)
+		}
+	}
+}
+
+func TestSchedulerStopTimeout(t *testing.T) {
+	clock := newFakeClock(t)
+	s := New(zerolog.Nop())
+	started := make(chan struct{})
+
+	release := make(chan struct{})
+
+	// The job ignores the ctx, Stop must not wait for it.
+	_ = s.AddInterval("stuck", time.Minute, 0, func(context.Context) error {
+		close(started)
+		<-release
+
+		return nil
+	})
+
+	s.Start(context.Background())
+	clock.WaitTimers(t, 1)
+	clock.Advance(time.Minute)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop error = %v, want DeadlineExceeded", err)
+	}
+
+	// The job finishes once released.
+	close(release)
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop error = %v", err)
+	}
+}
+
+func TestSchedulerStopNotStarted(t *testing.T) {
+	if err := New(zerolog.Nop()).Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+// AutoscalePolicy controls FanOut.Autoscale.
+type AutoscalePolicy struct {
+	// Min and Max bound the worker count, Min is at least 1 and Max at least Min.
+	Min uint
+	Max uint
+	// Interval between scaling decisions, 0 or less defaults to DefaultAutoscaleInterval.
+	Interval time.Duration
+	// TargetWait is the acceptable estimated queue wait, queued inputs * mean latency / workers.  Workers are added
+	// while the estimate exceeds it.
+	TargetWait time.Duration
+	// Step is the number of workers added or removed per decision, 0 defaults to 1.
+	Step uint
+}
+
+// DefaultAutoscaleInterval is the interval used by Autoscale when the policy interval is not positive.
+const DefaultAutoscaleInterval = time.Second
+
+// Target returns the worker count for the current count and the observed queue depth, idle workers and mean
+// processing latency.  A latency of 0 (no inputs completed) scales up only if all workers are busy.
+func (p AutoscalePolicy) Target(workers uint, queued, idle int, latency time.Duration) uint {
+	step := max(p.Step, 1)
+	lower := max(p.Min, 1)
+	upper := max(p.Max, lower)
+
+	switch {
+	case queued > 0 && latency == 0 && idle == 0:
+		workers += step
+	case queued > 0 && latency > 0 && time.Duration(queued)*latency/time.Duration(max(workers, 1)) > p.TargetWait:
+		workers += step
+	case queued == 0 && idle > 0:
+		workers -= min(step, uint(idle), workers)
+	}
+
+	return min(max(workers, lower), upper)
+}
+
+// Autoscale resizes the pool every policy interval until the ctx is done, based on the queue depth and the mean
+// processing latency over the interval.  This blocks, it is intended to be run in a separate go routine.
+func (f *FanOut[I]) Autoscale(ctx context.Context, policy AutoscalePolicy) {
+	interval := policy.Interval
+	if interval <= 0 {
+		interval = DefaultAutoscaleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := f.counters.duration.snapshot()
+
+	for {
+		select {
+		case <-ticker.C:
+			s := f.Stats()
+
+			var latency time.Duration
+			if n := s.Duration.Count - last.Count; n > 0 {
+				latency = (s.Duration.Sum - last.Sum) / time.Duration(n)
+			}
+
+			last = s.Duration
+
+			workers := f.WorkerCount()
+			if target := policy.Target(workers, s.QueuedTotal(), s.Idle, latency); target != workers {
+				f.Resize(target)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
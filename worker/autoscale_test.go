@@ -0,0 +1,94 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+func TestAutoscalePolicyTarget(t *testing.T) {
+	p := worker.AutoscalePolicy{Min: 2, Max: 8, TargetWait: 100 * time.Millisecond, Step: 2}
+
+	tests := []struct {
+		name    string
+		workers uint
+		queued  int
+		idle    int
+		latency time.Duration
+		want    uint
+	}{
+		{"steady", 4, 10, 0, 10 * time.Millisecond, 4},
+		{"wait exceeded", 4, 100, 0, 10 * time.Millisecond, 6},
+		{"max", 8, 100, 0, 10 * time.Millisecond, 8},
+		{"no latency all busy", 4, 1, 0, 0, 6},
+		{"no latency idle", 4, 1, 1, 0, 4},
+		{"idle", 4, 0, 3, 0, 2},
+		{"idle one", 4, 0, 1, 0, 3},
+		{"min", 2, 0, 2, 0, 2},
+		{"below min", 1, 0, 0, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.Target(tt.workers, tt.queued, tt.idle, tt.latency))
+		})
+	}
+
+	assert.Equal(t, uint(1), worker.AutoscalePolicy{}.Target(5, 0, 5, 0), "defaults")
+}
+
+func TestFanOutAutoscale(t *testing.T) {
+	w := worker.NewFanOut[int](1, 100)
+	release := make(chan struct{})
+	done := make(chan error)
+
+	for _, i := range testInts(50) {
+		require.NoError(t, w.Invoke(i))
+	}
+
+	go func() {
+		done <- w.ProcessContext(context.Background(), func(context.Context, int) error {
+			<-release
+
+			return nil
+		})
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		w.Autoscale(ctx, worker.AutoscalePolicy{Min: 1, Max: 4, Interval: time.Millisecond})
+	}()
+
+	assert.Eventually(t, func() bool { return w.WorkerCount() == 4 }, time.Second, time.Millisecond,
+		"queue backed up, all busy")
+
+	close(release)
+
+	assert.Eventually(t, func() bool { return w.WorkerCount() == 1 }, time.Second, time.Millisecond,
+		"queue drained, idle")
+
+	cancel()
+	<-stopped
+
+	w.Close()
+	require.NoError(t, <-done)
+	assert.Equal(t, uint64(50), w.Stats().Processed)
+}
+
+func TestFanOutAutoscaleDefaultInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NotPanics(t, func() {
+		worker.NewFanOut[int](1, 0).Autoscale(ctx, worker.AutoscalePolicy{Max: 4})
+	})
+}
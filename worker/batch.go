@@ -0,0 +1,270 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+// BatchProcessorFunc processes a batch of inputs.  The batch slice is not reused and may be retained.
+type BatchProcessorFunc[I any] func(ctx context.Context, batch []I) error
+
+// BatchFanOut fans out to `workerCount` coroutines that each collect inputs into batches of up to `maxSize`,
+// processing a batch when it is full or `maxWait` after its first input, whichever comes first.
+type BatchFanOut[I any] struct {
+	workerCount uint
+	maxSize     uint
+	maxWait     time.Duration
+	inputs      chan I
+	gate        *gate
+	onError     ErrorFunc[[]I]
+	onPanic     PanicFunc[[]I]
+}
+
+// NewBatchFanOut creates a batching pool.  A `maxWait` of 0 only flushes full batches, and the final batch on Close.
+func NewBatchFanOut[I any](workerCount, bufferSize, maxSize uint, maxWait time.Duration) *BatchFanOut[I] {
+	return &BatchFanOut[I]{
+		workerCount: workerCount,
+		maxSize:     max(maxSize, 1),
+		maxWait:     maxWait,
+		inputs:      make(chan I, bufferSize),
+		gate:        newGate(),
+	}
+}
+
+// WithErrorFunc reports processor errors to `fn` with the failed batch, instead of collecting them for
+// ProcessContext.
+func (f *BatchFanOut[I]) WithErrorFunc(fn ErrorFunc[[]I]) *BatchFanOut[I] {
+	f.onError = fn
+
+	return f
+}
+
+// WithPanicFunc reports panics recovered from processors to `fn`, see FanOut.WithPanicFunc.
+func (f *BatchFanOut[I]) WithPanicFunc(fn PanicFunc[[]I]) *BatchFanOut[I] {
+	f.onPanic = fn
+
+	return f
+}
+
+// Close closes the input channel, workers flush their partial batches.  See FanOut.Close.
+func (f *BatchFanOut[I]) Close() {
+	f.gate.close(func() {
+		close(f.inputs)
+	})
+}
+
+// Invoke adds the data to the worker for batching, see FanOut.Invoke.
+func (f *BatchFanOut[I]) Invoke(input I) error {
+	return send(context.Background(), f.gate, f.inputs, input)
+}
+
+// InvokeContext adds the data to the worker for batching, see FanOut.InvokeContext.
+func (f *BatchFanOut[I]) InvokeContext(ctx context.Context, input I) error {
+	return send(ctx, f.gate, f.inputs, input)
+}
+
+// InvokeTimeout adds the data to the worker for batching, see FanOut.InvokeTimeout.
+func (f *BatchFanOut[I]) InvokeTimeout(input I, timeout time.Duration) error {
+	return sendTimeout(f.gate, f.inputs, input, timeout)
+}
+
+// TryInvoke adds the data to the worker for batching if it can be accepted immediately, see FanOut.TryInvoke.
+func (f *BatchFanOut[I]) TryInvoke(input I) bool {
+	return trySend(f.gate, f.inputs, input)
+}
+
+// QueueDepth returns the number of inputs waiting in the buffer, not including inputs collected in batches.
+func (f *BatchFanOut[I]) QueueDepth() int {
+	return len(f.inputs)
+}
+
+// ProcessContext handles all batches until the input channel is closed or the ctx is done.  Partial batches are
+// dropped when the ctx is done, otherwise see FanOut.ProcessContext.
+func (f *BatchFanOut[I]) ProcessContext(ctx context.Context, p BatchProcessorFunc[I]) error {
+	errs := &errorCollector[[]I]{onError: f.onError, onPanic: f.onPanic}
+
+	return processWorkers(ctx, f.gate.shutdown, errs, func(spawn func(func() bool)) {
+		for range f.workerCount {
+			spawn(func() bool {
+				return workBatches(ctx, f.inputs, f.maxSize, f.maxWait, p, errs)
+			})
+		}
+	})
+}
+
+// HashedBatchFanOut is a BatchFanOut that hashes the input so that the same keys are always batched by the same
+// worker, see HashedFanOut.
+type HashedBatchFanOut[I any] struct {
+	workerCount uint
+	maxSize     uint
+	maxWait     time.Duration
+	inputs      []chan I
+	hasher      HashFunc[I]
+	assign      AssignFunc
+	gate        *gate
+	onError     ErrorFunc[[]I]
+	onPanic     PanicFunc[[]I]
+}
+
+// NewHashedBatchFanOut creates a keyed batching pool, see NewBatchFanOut.
+func NewHashedBatchFanOut[I any](workerCount, bufferSize, maxSize uint, maxWait time.Duration,
+	hasher HashFunc[I],
+) *HashedBatchFanOut[I] {
+	inputs := make([]chan I, workerCount)
+	for i := range workerCount {
+		inputs[i] = make(chan I, bufferSize)
+	}
+
+	return &HashedBatchFanOut[I]{
+		workerCount: workerCount,
+		maxSize:     max(maxSize, 1),
+		maxWait:     maxWait,
+		inputs:      inputs,
+		hasher:      hasher,
+		assign:      ModuloAssign,
+		gate:        newGate(),
+	}
+}
+
+// WithErrorFunc reports processor errors to `fn` with the failed batch, instead of collecting them for
+// ProcessContext.
+func (f *HashedBatchFanOut[I]) WithErrorFunc(fn ErrorFunc[[]I]) *HashedBatchFanOut[I] {
+	f.onError = fn
+
+	return f
+}
+
+// WithPanicFunc reports panics recovered from processors to `fn`, see FanOut.WithPanicFunc.
+func (f *HashedBatchFanOut[I]) WithPanicFunc(fn PanicFunc[[]I]) *HashedBatchFanOut[I] {
+	f.onPanic = fn
+
+	return f
+}
+
+// WithAssignFunc sets how hashes are assigned to workers, see HashedFanOut.WithAssignFunc.
+func (f *HashedBatchFanOut[I]) WithAssignFunc(fn AssignFunc) *HashedBatchFanOut[I] {
+	f.assign = fn
+
+	return f
+}
+
+// Close closes the input channels, workers flush their partial batches.  See FanOut.Close.
+func (f *HashedBatchFanOut[I]) Close() {
+	f.gate.close(func() {
+		for _, c := range f.inputs {
+			close(c)
+		}
+	})
+}
+
+// Invoke adds the data to the keyed worker for batching, see FanOut.Invoke.
+func (f *HashedBatchFanOut[I]) Invoke(input I) error {
+	return send(context.Background(), f.gate, f.inputFor(input), input)
+}
+
+// InvokeContext adds the data to the keyed worker for batching, see FanOut.InvokeContext.
+func (f *HashedBatchFanOut[I]) InvokeContext(ctx context.Context, input I) error {
+	return send(ctx, f.gate, f.inputFor(input), input)
+}
+
+// InvokeTimeout adds the data to the keyed worker for batching, see FanOut.InvokeTimeout.
+func (f *HashedBatchFanOut[I]) InvokeTimeout(input I, timeout time.Duration) error {
+	return sendTimeout(f.gate, f.inputFor(input), input, timeout)
+}
+
+// TryInvoke adds the data to the keyed worker for batching if it can be accepted immediately, see FanOut.TryInvoke.
+func (f *HashedBatchFanOut[I]) TryInvoke(input I) bool {
+	return trySend(f.gate, f.inputFor(input), input)
+}
+
+// QueueDepths returns the number of inputs waiting for each worker, not including inputs collected in batches.
+func (f *HashedBatchFanOut[I]) QueueDepths() []int {
+	out := make([]int, len(f.inputs))
+	for i, c := range f.inputs {
+		out[i] = len(c)
+	}
+
+	return out
+}
+
+func (f *HashedBatchFanOut[I]) inputFor(input I) chan I {
+	return f.inputs[f.assign(f.hasher(input), f.workerCount)]
+}
+
+// ProcessContext handles all batches until the input channels are closed or the ctx is done, see
+// BatchFanOut.ProcessContext.
+func (f *HashedBatchFanOut[I]) ProcessContext(ctx context.Context, p BatchProcessorFunc[I]) error {
+	errs := &errorCollector[[]I]{onError: f.onError, onPanic: f.onPanic}
+
+	return processWorkers(ctx, f.gate.shutdown, errs, func(spawn func(func() bool)) {
+		for _, c := range f.inputs {
+			spawn(func() bool {
+				return workBatches(ctx, c, f.maxSize, f.maxWait, p, errs)
+			})
+		}
+	})
+}
+
+// workBatches collects inputs into batches until the channel is closed, flushing the final batch, or the ctx is done.
+// Returns true if interrupted by the ctx.
+func workBatches[I any](ctx context.Context, inputs <-chan I, maxSize uint, maxWait time.Duration,
+	p BatchProcessorFunc[I], errs *errorCollector[[]I],
+) bool {
+	var (
+		batch  []I
+		timer  = time.NewTimer(maxWait)
+		expire <-chan time.Time
+	)
+
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+
+		expire = nil
+
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := safeProcess(ctx, ContextProcessorFunc[[]I](p), batch); err != nil {
+			errs.report(ctx, batch, err)
+		}
+
+		batch = nil
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return true
+		case <-expire:
+			flush()
+		case input, ok := <-inputs:
+			if !ok {
+				flush()
+
+				return false
+			}
+
+			if batch == nil {
+				batch = make([]I, 0, maxSize)
+
+				if maxWait > 0 {
+					timer.Reset(maxWait)
+					expire = timer.C
+				}
+			}
+
+			batch = append(batch, input)
+
+			if uint(len(batch)) >= maxSize {
+				flush()
+			}
+		}
+	}
+}
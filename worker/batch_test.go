@@ -0,0 +1,162 @@
+package worker_test
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+type batches struct {
+	mu  sync.Mutex
+	out [][]int
+}
+
+func (b *batches) process(_ context.Context, batch []int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.out = append(b.out, batch)
+
+	return nil
+}
+
+func TestBatchFanOutSize(t *testing.T) {
+	w := worker.NewBatchFanOut[int](1, 10, 3, 0)
+	for _, i := range testInts(7) {
+		w.Invoke(i)
+	}
+
+	w.Close()
+
+	b := &batches{}
+	require.NoError(t, w.ProcessContext(context.Background(), b.process))
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}, {7}}, b.out, "final partial batch flushed on close")
+}
+
+func TestBatchFanOutWait(t *testing.T) {
+	w := worker.NewBatchFanOut[int](2, 0, 100, 5*time.Millisecond)
+	out := make(chan []int)
+
+	go func() {
+		assert.NoError(t, w.ProcessContext(context.Background(), func(_ context.Context, batch []int) error {
+			out <- batch
+
+			return nil
+		}))
+		close(out)
+	}()
+
+	w.Invoke(1)
+	w.Invoke(2)
+	assert.ElementsMatch(t, []int{1, 2}, collectUntil(t, out, 2))
+
+	w.Invoke(3)
+	assert.Equal(t, []int{3}, <-out, "flushed by time while open")
+
+	w.Close()
+
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+// collectUntil receives from the batch channel until `n` more inputs are collected.
+func collectUntil(t *testing.T, out <-chan []int, n int) []int {
+	t.Helper()
+
+	var got []int
+
+	for len(got) < n {
+		select {
+		case batch := <-out:
+			got = append(got, batch...)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	return got
+}
+
+func TestBatchFanOutErrorFunc(t *testing.T) {
+	var failed [][]int
+
+	w := worker.NewBatchFanOut[int](1, 10, 2, 0).WithErrorFunc(func(batch []int, err error) {
+		assert.ErrorIs(t, err, errOdd)
+
+		failed = append(failed, batch)
+	})
+
+	for _, i := range testInts(5) {
+		w.Invoke(i)
+	}
+
+	w.Close()
+
+	err := w.ProcessContext(context.Background(), func(_ context.Context, batch []int) error {
+		if batch[0] == 3 {
+			return errOdd
+		}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{3, 4}}, failed)
+}
+
+func TestBatchFanOutCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := worker.NewBatchFanOut[int](1, 10, 10, 0)
+
+	require.NoError(t, w.InvokeContext(ctx, 1))
+	cancel()
+
+	b := &batches{}
+	require.ErrorIs(t, w.ProcessContext(ctx, b.process), context.Canceled)
+	require.ErrorIs(t, w.InvokeContext(context.Background(), 2), worker.ErrShutdown)
+	assert.Empty(t, b.out, "partial batch dropped")
+}
+
+func TestHashedBatchFanOut(t *testing.T) {
+	key := func(i int) string { return strconv.Itoa(i % 3) }
+	w := worker.NewHashedBatchFanOut[int](3, 0, 4, time.Millisecond, worker.StringHasher(key))
+
+	go func() {
+		for _, i := range testInts(50) {
+			assert.NoError(t, w.InvokeContext(context.Background(), i))
+		}
+
+		w.Close()
+	}()
+
+	b := &batches{}
+	require.NoError(t, w.ProcessContext(context.Background(), b.process))
+
+	perKey := map[string][]int{}
+
+	for _, batch := range b.out {
+		assert.LessOrEqual(t, len(batch), 4)
+
+		for _, i := range batch {
+			perKey[key(i)] = append(perKey[key(i)], i)
+		}
+	}
+
+	total := 0
+
+	for k, ii := range perKey {
+		assert.True(t, slices.IsSorted(ii), "key %s in order", k)
+
+		total += len(ii)
+	}
+
+	assert.Equal(t, 50, total)
+}
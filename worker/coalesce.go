@@ -0,0 +1,278 @@
+package worker
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Coalescer processes only the newest input for each key, once the key has been quiet.  Inputs replace any input
+// waiting for the same key, and are processed after no input for the key has arrived for the quiet period, or after
+// the max wait since the first replaced input, whichever is first.  This collapses bursts of events, such as cache
+// invalidations, into a single call.
+//
+// Inputs for the same key are never processed concurrently, an input arriving while its key is processing waits for
+// it to finish.
+type Coalescer[I any, K comparable] struct {
+	workerCount uint
+	keyFunc     KeyFunc[I, K]
+	quiet       time.Duration
+	maxWait     time.Duration
+	onError     ErrorFunc[I]
+	onPanic     PanicFunc[I]
+
+	mu       sync.Mutex
+	pending  map[K]*coalesced[I]
+	inflight map[K]struct{}
+	closed   bool
+	shutdown bool
+	wake     chan struct{}
+}
+
+type coalesced[I any] struct {
+	input I
+	first time.Time
+	last  time.Time
+}
+
+type keyedInput[I any, K comparable] struct {
+	key   K
+	input I
+	due   time.Time
+}
+
+// NewCoalescer creates a coalescing pool.  A `maxWait` of 0 waits for the quiet period without limit.
+func NewCoalescer[I any, K comparable](workerCount uint, quiet, maxWait time.Duration,
+	keyFunc KeyFunc[I, K],
+) *Coalescer[I, K] {
+	return &Coalescer[I, K]{
+		workerCount: workerCount,
+		keyFunc:     keyFunc,
+		quiet:       quiet,
+		maxWait:     maxWait,
+		pending:     make(map[K]*coalesced[I]),
+		inflight:    make(map[K]struct{}),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// WithErrorFunc reports processor errors to `fn`, see FanOut.WithErrorFunc.
+func (c *Coalescer[I, K]) WithErrorFunc(fn ErrorFunc[I]) *Coalescer[I, K] {
+	c.onError = fn
+
+	return c
+}
+
+// WithPanicFunc reports panics recovered from processors to `fn`, see FanOut.WithPanicFunc.
+func (c *Coalescer[I, K]) WithPanicFunc(fn PanicFunc[I]) *Coalescer[I, K] {
+	c.onPanic = fn
+
+	return c
+}
+
+// Invoke adds the data, replacing any input waiting for the same key.  It never blocks.  Returns ErrClosed after
+// Close, or ErrShutdown once ProcessContext's ctx is done.
+func (c *Coalescer[I, K]) Invoke(input I) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.closed:
+		return ErrClosed
+	case c.shutdown:
+		return ErrShutdown
+	}
+
+	now := time.Now()
+	k := c.keyFunc(input)
+
+	p, ok := c.pending[k]
+	if !ok {
+		p = &coalesced[I]{first: now}
+		c.pending[k] = p
+	}
+
+	p.input = input
+	p.last = now
+
+	c.notify()
+
+	return nil
+}
+
+// Close stops accepting inputs.  Waiting inputs are processed immediately, without waiting for the quiet period.
+func (c *Coalescer[I, K]) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	c.notify()
+}
+
+// QueueDepth returns the number of keys waiting.
+func (c *Coalescer[I, K]) QueueDepth() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.pending)
+}
+
+// Process handles all inputs until closed and drained.
+func (c *Coalescer[I, K]) Process(p ProcessorFunc[I]) {
+	process(c.ProcessContext, p)
+}
+
+// ProcessContext handles all inputs until closed and drained, or the ctx is done.  Inputs still waiting when the ctx
+// is done are discarded.  See FanOut.ProcessContext for cancellation and error handling.
+func (c *Coalescer[I, K]) ProcessContext(ctx context.Context, p ContextProcessorFunc[I]) error {
+	errs := &errorCollector[I]{onError: c.onError, onPanic: c.onPanic}
+	ready := make(chan keyedInput[I, K])
+
+	return processWorkers(ctx, c.stop, errs, func(spawn func(func() bool)) {
+		spawn(func() bool {
+			defer close(ready)
+
+			return c.dispatch(ctx, ready)
+		})
+
+		for range c.workerCount {
+			spawn(func() bool {
+				for in := range ready {
+					if err := safeProcess(ctx, p, in.input); err != nil {
+						errs.report(ctx, in.input, err)
+					}
+
+					c.finish(in.key)
+				}
+
+				return false
+			})
+		}
+	})
+}
+
+// dispatch sends inputs to the workers as they become due.  Returns true if interrupted by the ctx.
+func (c *Coalescer[I, K]) dispatch(ctx context.Context, ready chan<- keyedInput[I, K]) bool {
+	for {
+		if ctx.Err() != nil {
+			return true
+		}
+
+		due, wait, done := c.due(time.Now())
+		if done {
+			return false
+		}
+
+		for _, in := range due {
+			select {
+			case ready <- in:
+			case <-ctx.Done():
+				return true
+			}
+		}
+
+		if len(due) > 0 {
+			continue
+		}
+
+		var timer *time.Timer
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-c.wake:
+		case <-timerC(timer):
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// due removes the inputs due at `now`, ordered by due time, and marks their keys in flight.  Otherwise returns how
+// long until the next input is due, 0 if waiting on keys in flight.  done is true once closed and drained.
+func (c *Coalescer[I, K]) due(now time.Time) (due []keyedInput[I, K], wait time.Duration, done bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed && len(c.pending) == 0 {
+		return nil, 0, true
+	}
+
+	var next time.Time
+
+	for k, p := range c.pending {
+		if _, ok := c.inflight[k]; ok {
+			continue
+		}
+
+		at := p.last.Add(c.quiet)
+		if limit := p.first.Add(c.maxWait); c.maxWait > 0 && limit.Before(at) {
+			at = limit
+		}
+
+		if c.closed || !at.After(now) {
+			due = append(due, keyedInput[I, K]{key: k, input: p.input, due: at})
+
+			continue
+		}
+
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+
+	slices.SortFunc(due, func(a, b keyedInput[I, K]) int { return a.due.Compare(b.due) })
+
+	for _, in := range due {
+		delete(c.pending, in.key)
+		c.inflight[in.key] = struct{}{}
+	}
+
+	if !next.IsZero() {
+		wait = next.Sub(now)
+	}
+
+	return due, wait, false
+}
+
+// finish clears the key in flight, waking the dispatcher if an input is waiting for it.
+func (c *Coalescer[I, K]) finish(k K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.inflight, k)
+
+	if _, ok := c.pending[k]; ok {
+		c.notify()
+	}
+}
+
+// stop rejects new inputs once the ctx is done.
+func (c *Coalescer[I, K]) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shutdown = true
+}
+
+// notify wakes the dispatcher, must be called with c.mu held.
+func (c *Coalescer[I, K]) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// timerC returns the timer channel, or nil (blocking forever) for no timer.
+func timerC(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+
+	return t.C
+}
@@ -0,0 +1,189 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+func TestCoalescerCloseFlushes(t *testing.T) {
+	w := worker.NewCoalescer[tenantInput](2, time.Hour, 0, tenantKey)
+
+	for i := range 5 {
+		require.NoError(t, w.Invoke(tenantInput{"a", i + 1}))
+	}
+
+	require.NoError(t, w.Invoke(tenantInput{"b", 1}))
+	assert.Equal(t, 2, w.QueueDepth())
+
+	w.Close()
+	assert.ErrorIs(t, w.Invoke(tenantInput{"a", 6}), worker.ErrClosed)
+
+	var (
+		mu  sync.Mutex
+		out []tenantInput
+	)
+
+	w.Process(func(in tenantInput) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		out = append(out, in)
+	})
+
+	assert.ElementsMatch(t, []tenantInput{{"a", 5}, {"b", 1}}, out)
+	assert.Equal(t, 0, w.QueueDepth())
+}
+
+func TestCoalescerQuiet(t *testing.T) {
+	const quiet = 30 * time.Millisecond
+
+	w := worker.NewCoalescer[tenantInput](1, quiet, 0, tenantKey)
+	out := make(chan tenantInput, 10)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		w.Process(func(in tenantInput) { out <- in })
+	}()
+
+	var last time.Time
+
+	for i := range 5 {
+		require.NoError(t, w.Invoke(tenantInput{"a", i + 1}))
+		last = time.Now()
+
+		time.Sleep(quiet / 5)
+	}
+
+	got := <-out
+	assert.Equal(t, tenantInput{"a", 5}, got)
+	assert.GreaterOrEqual(t, time.Since(last), quiet)
+
+	w.Close()
+	<-done
+	assert.Empty(t, out)
+}
+
+func TestCoalescerMaxWait(t *testing.T) {
+	w := worker.NewCoalescer[tenantInput](1, 50*time.Millisecond, 40*time.Millisecond, tenantKey)
+
+	var processed atomic.Int32
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		w.Process(func(tenantInput) { processed.Add(1) })
+	}()
+
+	// Never quiet, only the max wait flushes.
+	for i := range 30 {
+		require.NoError(t, w.Invoke(tenantInput{"a", i}))
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assert.GreaterOrEqual(t, processed.Load(), int32(2))
+
+	w.Close()
+	<-done
+}
+
+func TestCoalescerKeyNotConcurrent(t *testing.T) {
+	w := worker.NewCoalescer[tenantInput](4, time.Millisecond, 0, tenantKey)
+
+	var (
+		running, maxRunning atomic.Int32
+		processed           atomic.Int32
+	)
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		w.Process(func(in tenantInput) {
+			n := running.Add(1)
+			if n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+
+			if in.n == 1 {
+				started <- struct{}{}
+				<-release
+			}
+
+			processed.Add(1)
+			running.Add(-1)
+		})
+	}()
+
+	require.NoError(t, w.Invoke(tenantInput{"a", 1}))
+	<-started
+
+	require.NoError(t, w.Invoke(tenantInput{"a", 2}))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 1, w.QueueDepth(), "waits for the key in flight")
+
+	close(release)
+	w.Close()
+	<-done
+
+	assert.Equal(t, int32(2), processed.Load())
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+func TestCoalescerCancel(t *testing.T) {
+	w := worker.NewCoalescer[tenantInput](1, time.Hour, 0, tenantKey)
+	require.NoError(t, w.Invoke(tenantInput{"a", 1}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := w.ProcessContext(ctx, func(context.Context, tenantInput) error {
+		t.Error("unexpected call")
+
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, w.Invoke(tenantInput{"a", 2}), worker.ErrShutdown)
+}
+
+func TestCoalescerErrors(t *testing.T) {
+	w := worker.NewCoalescer[int](1, time.Hour, 0, func(i int) int { return i })
+
+	for i := range 4 {
+		require.NoError(t, w.Invoke(i))
+	}
+
+	w.Close()
+
+	err := w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+		if i%2 == 1 {
+			return errOdd
+		}
+
+		if i == 2 {
+			panic(errBoom)
+		}
+
+		return nil
+	})
+	require.ErrorIs(t, err, errOdd)
+
+	var panicErr *worker.PanicError
+
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, errBoom, panicErr.Value)
+}
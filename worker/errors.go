@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrShutdown is returned when adding inputs to a pool that is no longer processing, because the ctx passed to
+// ProcessContext is done.
+var ErrShutdown = errors.New("worker: shutting down")
+
+// ErrClosed is returned when inputs are added after Close.
+var ErrClosed = errors.New("worker: closed")
+
+// ContextProcessorFunc processes a single input, returning any error to be reported.
+type ContextProcessorFunc[I any] func(ctx context.Context, input I) error
+
+// ErrorFunc receives errors returned by processors.
+type ErrorFunc[I any] func(input I, err error)
+
+// discardKey marks the ctx of Process, which discards the errors so they are not collected.
+type discardKey struct{}
+
+// process runs `processContext` for Process, adapting the processor and discarding errors.  Panics are still reported
+// to the PanicFunc or logged.
+func process[I any](processContext func(context.Context, ContextProcessorFunc[I]) error, p ProcessorFunc[I]) {
+	ctx := context.WithValue(context.Background(), discardKey{}, true)
+
+	_ = processContext(ctx, func(_ context.Context, input I) error {
+		p(input)
+
+		return nil
+	})
+}
+
+// errorCollector reports processor errors to the ErrorFunc if defined, otherwise collects them.  Panics are reported
+// to the PanicFunc if defined, otherwise logged with LogPanic and handled as errors.
+type errorCollector[I any] struct {
+	mu      sync.Mutex
+	errs    []error
+	onError ErrorFunc[I]
+	onPanic PanicFunc[I]
+}
+
+func (c *errorCollector[I]) report(ctx context.Context, input I, err error) {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		if c.onPanic != nil {
+			c.onPanic(ctx, input, panicErr)
+
+			return
+		}
+
+		// Errors may be discarded, never lose a panic.
+		LogPanic(ctx, input, panicErr)
+	}
+
+	if c.onError != nil {
+		c.onError(input, err)
+
+		return
+	}
+
+	if ctx.Value(discardKey{}) != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errs = append(c.errs, err)
+}
+
+// err returns the collected errors, with the ctx error if processing was interrupted.
+func (c *errorCollector[I]) err(ctx context.Context, interrupted bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := c.errs
+	if interrupted {
+		errs = append(errs, ctx.Err())
+	}
+
+	return errors.Join(errs...)
+}
+
+// exitReason is why a worker stopped.
+type exitReason int
+
+const (
+	exitClosed exitReason = iota
+	exitCancelled
+	exitQuit
+)
+
+// work processes inputs until the channel is closed, the ctx is done or quit is closed.
+func work[I any](ctx context.Context, inputs <-chan I, quit <-chan struct{}, p ContextProcessorFunc[I],
+	errs *errorCollector[I], c *counters,
+) exitReason {
+	c.running.Add(1)
+	defer c.running.Add(-1)
+
+	for {
+		// Check first, select does not prioritize between ready cases.
+		if ctx.Err() != nil {
+			return exitCancelled
+		}
+
+		select {
+		case <-quit:
+			return exitQuit
+		default:
+		}
+
+		select {
+		case <-ctx.Done():
+			return exitCancelled
+		case <-quit:
+			return exitQuit
+		case input, ok := <-inputs:
+			if !ok {
+				return exitClosed
+			}
+
+			start := c.start()
+			err := safeProcess(ctx, p, input)
+			c.done(start, err)
+
+			if err != nil {
+				errs.report(ctx, input, err)
+			}
+		}
+	}
+}
+
+// processWorkers is the shared ProcessContext lifecycle.  `start` launches the workers with `spawn`, each worker
+// returns true if it stopped because the ctx is done.  `shutdown` is called when the ctx is done, rejecting new
+// inputs.  Returns the collected errors once all workers have exited.
+func processWorkers[I any](ctx context.Context, shutdown func(), errs *errorCollector[I],
+	start func(spawn func(worker func() bool)),
+) error {
+	stop := context.AfterFunc(ctx, shutdown)
+	defer stop()
+
+	var (
+		wg          sync.WaitGroup
+		interrupted atomic.Bool
+	)
+
+	start(func(worker func() bool) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if worker() {
+				interrupted.Store(true)
+			}
+		}()
+	})
+
+	wg.Wait()
+
+	// The AfterFunc may not have run yet, stop cancels it.
+	if interrupted.Load() {
+		shutdown()
+	}
+
+	return errs.err(ctx, interrupted.Load())
+}
@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+)
+
+// FairFanOut round-robins between the keys (tenants) of the inputs, so a single key can not monopolize the workers.
+// Inputs for the same key are processed in order of arrival, but may be processed concurrently.
+type FairFanOut[I any, K comparable] struct {
+	scheduled[I]
+}
+
+// NewFairFanOut creates a pool buffering up to `bufferSize` inputs per key, so a busy key blocks only its own
+// producers.
+func NewFairFanOut[I any, K comparable](workerCount, bufferSize uint, keyFunc KeyFunc[I, K]) *FairFanOut[I, K] {
+	policy := &fairPolicy[I, K]{
+		keyFunc:  keyFunc,
+		queues:   make(map[K][]I),
+		capacity: int(max(bufferSize, 1)),
+	}
+
+	return &FairFanOut[I, K]{scheduled: scheduled[I]{workerCount: workerCount, queue: newSchedQueue[I](policy)}}
+}
+
+// WithErrorFunc reports processor errors to `fn`, see FanOut.WithErrorFunc.
+func (f *FairFanOut[I, K]) WithErrorFunc(fn ErrorFunc[I]) *FairFanOut[I, K] {
+	f.onError = fn
+
+	return f
+}
+
+// WithPanicFunc reports panics recovered from processors to `fn`, see FanOut.WithPanicFunc.
+func (f *FairFanOut[I, K]) WithPanicFunc(fn PanicFunc[I]) *FairFanOut[I, K] {
+	f.onPanic = fn
+
+	return f
+}
+
+// Invoke adds the data to the queue for its key, blocking until accepted.  See FanOut.Invoke.
+func (f *FairFanOut[I, K]) Invoke(input I) error {
+	_, err := f.queue.push(context.Background(), input, 0, true)
+
+	return err
+}
+
+// InvokeContext adds the data to the queue for its key, see FanOut.InvokeContext.
+func (f *FairFanOut[I, K]) InvokeContext(ctx context.Context, input I) error {
+	_, err := f.queue.push(ctx, input, 0, true)
+
+	return err
+}
+
+// TryInvoke adds the data to the queue for its key if it has room.  Returns false if the input was not accepted.
+func (f *FairFanOut[I, K]) TryInvoke(input I) bool {
+	ok, _ := f.queue.push(context.Background(), input, 0, false)
+
+	return ok
+}
+
+// fairPolicy keeps a queue per key, and a ring of the keys with waiting inputs.
+type fairPolicy[I any, K comparable] struct {
+	keyFunc  KeyFunc[I, K]
+	queues   map[K][]I
+	ring     []K
+	capacity int
+	size     int
+}
+
+func (p *fairPolicy[I, K]) push(input I, _ uint) {
+	k := p.keyFunc(input)
+
+	q := p.queues[k]
+	if len(q) == 0 {
+		p.ring = append(p.ring, k)
+	}
+
+	p.queues[k] = append(q, input)
+	p.size++
+}
+
+func (p *fairPolicy[I, K]) pop() I {
+	k := p.ring[0]
+	p.ring = p.ring[1:]
+
+	q := p.queues[k]
+	input := q[0]
+
+	if len(q) == 1 {
+		delete(p.queues, k)
+	} else {
+		var zero I
+
+		q[0] = zero
+		p.queues[k] = q[1:]
+		p.ring = append(p.ring, k)
+	}
+
+	p.size--
+
+	return input
+}
+
+func (p *fairPolicy[I, K]) len() int {
+	return p.size
+}
+
+func (p *fairPolicy[I, K]) full(input I, _ uint) bool {
+	return len(p.queues[p.keyFunc(input)]) >= p.capacity
+}
@@ -0,0 +1,82 @@
+package worker_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+type tenantInput struct {
+	tenant string
+	n      int
+}
+
+func tenantKey(in tenantInput) string {
+	return in.tenant
+}
+
+func TestFairFanOut(t *testing.T) {
+	w := worker.NewFairFanOut[tenantInput](1, 10, tenantKey)
+
+	for i := range 4 {
+		require.NoError(t, w.Invoke(tenantInput{"noisy", i + 1}))
+	}
+
+	require.NoError(t, w.Invoke(tenantInput{"b", 1}))
+	require.NoError(t, w.Invoke(tenantInput{"c", 1}))
+	require.NoError(t, w.Invoke(tenantInput{"b", 2}))
+	assert.Equal(t, 7, w.QueueDepth())
+
+	w.Close()
+
+	var out []tenantInput
+
+	w.Process(func(in tenantInput) {
+		out = append(out, in)
+	})
+
+	assert.Equal(t, []tenantInput{
+		{"noisy", 1}, {"b", 1}, {"c", 1},
+		{"noisy", 2}, {"b", 2},
+		{"noisy", 3},
+		{"noisy", 4},
+	}, out)
+}
+
+func TestFairFanOutBackpressure(t *testing.T) {
+	w := worker.NewFairFanOut[tenantInput](2, 2, tenantKey)
+
+	assert.True(t, w.TryInvoke(tenantInput{"noisy", 1}))
+	assert.True(t, w.TryInvoke(tenantInput{"noisy", 2}))
+	assert.False(t, w.TryInvoke(tenantInput{"noisy", 3}), "noisy tenant full")
+	assert.True(t, w.TryInvoke(tenantInput{"quiet", 1}), "other tenants unaffected")
+
+	blocked := make(chan error)
+
+	go func() {
+		blocked <- w.Invoke(tenantInput{"noisy", 3})
+	}()
+
+	processed := int64(0)
+	done := make(chan error)
+
+	go func() {
+		done <- w.ProcessContext(context.Background(), func(context.Context, tenantInput) error {
+			atomic.AddInt64(&processed, 1)
+
+			return nil
+		})
+	}()
+
+	require.NoError(t, <-blocked, "accepted once the tenant queue drains")
+
+	w.Close()
+	require.NoError(t, <-done)
+	assert.Equal(t, int64(4), processed)
+	require.ErrorIs(t, w.InvokeContext(context.Background(), tenantInput{"quiet", 2}), worker.ErrClosed)
+}
@@ -1,44 +1,209 @@
 package worker
 
-import "sync"
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
 
 type ProcessorFunc[I any] func(I)
 
 func NewFanOut[I any](workerCount, bufferSize uint) *FanOut[I] {
-	return &FanOut[I]{workerCount: workerCount, inputs: make(chan I, bufferSize)}
+	return &FanOut[I]{workerCount: workerCount, inputs: make(chan I, bufferSize), gate: newGate(), counters: newCounters()}
 }
 
-// FanOut will fan out to `workerCount` total coroutines for processing.
+// FanOut will fan out to `workerCount` total coroutines for processing.  The worker count can be changed while
+// processing, see Resize.
 type FanOut[I any] struct {
+	mu          sync.Mutex
 	workerCount uint
 	inputs      chan I
+	gate        *gate
+	counters    *counters
+	onError     ErrorFunc[I]
+	onPanic     PanicFunc[I]
+	quits       []chan struct{}
+	run         *fanOutRun[I]
+}
+
+// fanOutRun is the state of an active ProcessContext, guarded by FanOut.mu.
+type fanOutRun[I any] struct {
+	ctx   context.Context //nolint:containedctx // shared by resized workers
+	p     ContextProcessorFunc[I]
+	errs  *errorCollector[I]
+	spawn func(worker func() bool)
+	// drained is set when any worker stops for close or cancellation, no further workers are started.
+	drained bool
+}
+
+// WithErrorFunc reports processor errors to `fn` as they occur, instead of collecting them for ProcessContext.
+// Use this for long-running pools.
+func (f *FanOut[I]) WithErrorFunc(fn ErrorFunc[I]) *FanOut[I] {
+	f.onError = fn
+
+	return f
 }
 
-// Close closes the input channels.  Invoke can not be called again after this call.
+// WithPanicFunc reports panics recovered from processors to `fn`.  By default panics are logged to the ctx logger
+// with LogPanic and handled as processor errors (PanicError).  The worker continues with the next input either way.
+func (f *FanOut[I]) WithPanicFunc(fn PanicFunc[I]) *FanOut[I] {
+	f.onPanic = fn
+
+	return f
+}
+
+// Close closes the input channel, blocked Invoke calls return ErrClosed.  Inputs added after Close return ErrClosed.
 func (f *FanOut[I]) Close() {
-	close(f.inputs)
+	f.gate.close(func() {
+		close(f.inputs)
+	})
+}
+
+// Invoke adds the data to the worker for processing, blocking until accepted.  Returns ErrClosed after Close, or
+// ErrShutdown if processing has been cancelled.
+func (f *FanOut[I]) Invoke(input I) error {
+	return send(context.Background(), f.gate, f.inputs, input)
+}
+
+// InvokeContext adds the data to the worker for processing.  Returns the ctx error if the ctx is done before the
+// input is accepted, otherwise see Invoke.
+func (f *FanOut[I]) InvokeContext(ctx context.Context, input I) error {
+	return send(ctx, f.gate, f.inputs, input)
+}
+
+// InvokeTimeout adds the data to the worker for processing.  Returns context.DeadlineExceeded if the input is not
+// accepted within the timeout, otherwise see Invoke.
+func (f *FanOut[I]) InvokeTimeout(input I, timeout time.Duration) error {
+	return sendTimeout(f.gate, f.inputs, input, timeout)
 }
 
-// Invoke adds the data to the worker for processing.
-func (f *FanOut[I]) Invoke(input I) {
-	f.inputs <- input
+// TryInvoke adds the data to the worker for processing if the buffer has room or a worker is ready.  Returns false if
+// the input was not accepted.
+func (f *FanOut[I]) TryInvoke(input I) bool {
+	return trySend(f.gate, f.inputs, input)
 }
 
-// Process handles all inputs until the input channel is closed.
+// QueueDepth returns the number of inputs waiting in the buffer.
+func (f *FanOut[I]) QueueDepth() int {
+	return len(f.inputs)
+}
+
+// Process handles all inputs until the input channel is closed.  Panics are logged with LogPanic unless a PanicFunc
+// is set.
 func (f *FanOut[I]) Process(p ProcessorFunc[I]) {
-	wg := sync.WaitGroup{}
+	process(f.ProcessContext, p)
+}
 
-	for i := uint(0); i < f.workerCount; i++ {
-		wg.Add(1)
+// ProcessContext handles all inputs until the input channel is closed or the ctx is done.  When the ctx is done
+// workers stop after their current input, unprocessed inputs are dropped and blocked Invoke calls return
+// ErrShutdown.
+// Processor errors are returned joined, along with the ctx error if processing was interrupted.  If an ErrorFunc is
+// defined errors are reported to it instead.  Panics are recovered per input, see WithPanicFunc.
+func (f *FanOut[I]) ProcessContext(ctx context.Context, p ContextProcessorFunc[I]) error {
+	errs := &errorCollector[I]{onError: f.onError, onPanic: f.onPanic}
+
+	err := processWorkers(ctx, f.gate.shutdown, errs, func(spawn func(func() bool)) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.run = &fanOutRun[I]{ctx: ctx, p: p, errs: errs, spawn: spawn}
+
+		for range f.workerCount {
+			f.startWorker(f.run)
+		}
+	})
+
+	f.mu.Lock()
+	f.run = nil
+	f.mu.Unlock()
+
+	return err
+}
 
-		go func() {
-			for i := range f.inputs {
-				p(i)
-			}
+// WorkerCount returns the requested number of workers.  While resizing down, workers finishing their current input
+// may briefly exceed this.
+func (f *FanOut[I]) WorkerCount() uint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-			wg.Done()
-		}()
+	return f.workerCount
+}
+
+// Resize changes the number of workers, with a minimum of 1.  While processing, workers are started immediately and
+// stopped workers finish their current input first, no inputs are dropped.  ProcessContext continues until the input
+// channel is closed and drained regardless of resizing.
+func (f *FanOut[I]) Resize(n uint) {
+	n = max(n, 1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.workerCount = n
+
+	r := f.run
+	if r == nil || r.drained {
+		return
+	}
+
+	for uint(len(f.quits)) < n {
+		f.startWorker(r)
+	}
+
+	for uint(len(f.quits)) > n {
+		last := len(f.quits) - 1
+		close(f.quits[last])
+		f.quits = f.quits[:last]
 	}
+}
+
+// startWorker must be called with f.mu held.  While the run is not drained at least one started worker has not
+// exited, so spawning never races with processWorkers returning.
+func (f *FanOut[I]) startWorker(r *fanOutRun[I]) {
+	quit := make(chan struct{})
+	f.quits = append(f.quits, quit)
+
+	r.spawn(func() bool {
+		reason := work(r.ctx, f.inputs, quit, r.p, r.errs, f.counters)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if reason != exitQuit {
+			r.drained = true
+		}
+
+		f.quits = slices.DeleteFunc(f.quits, func(q chan struct{}) bool { return q == quit })
+
+		return reason == exitCancelled
+	})
+}
+
+// Stats returns a snapshot of the pool usage.
+func (f *FanOut[I]) Stats() Stats {
+	running := int(f.counters.running.Load())
+	busy := int(f.counters.busy.Load())
+
+	return Stats{
+		Enqueued:  f.gate.enqueued.Load(),
+		Processed: f.counters.processed.Load(),
+		Failed:    f.counters.failed.Load(),
+		Busy:      busy,
+		Idle:      max(running-busy, 0),
+		Queued:    []int{len(f.inputs)},
+		Duration:  f.counters.duration.snapshot(),
+	}
+}
+
+// Log logs a summary of the pool statistics.
+func (f *FanOut[I]) Log(l zerolog.Logger) {
+	f.Stats().log(l)
+}
 
-	wg.Wait()
+// LogEvery logs a summary of the pool statistics every `interval` until the ctx is done.
+// This blocks, it is intended to be run in a separate go routine.
+func (f *FanOut[I]) LogEvery(ctx context.Context, l zerolog.Logger, interval time.Duration) {
+	logEvery(ctx, interval, func() { f.Log(l) })
 }
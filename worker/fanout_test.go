@@ -1,12 +1,17 @@
 package worker_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/bir/iken/worker"
 )
@@ -149,3 +154,216 @@ func ExampleNewFanOut() {
 	// Output:
 	// [{CCCCCCCCCCC 2 11} {BBBB 1 4} {A 0 1}]
 }
+
+var errOdd = errors.New("odd")
+
+func TestFanOutProcessContext(t *testing.T) {
+	w := worker.NewFanOut[int](4, 0)
+
+	go func() {
+		for _, i := range testInts(10) {
+			assert.NoError(t, w.InvokeContext(context.Background(), i))
+		}
+
+		w.Close()
+	}()
+
+	sum := int64(0)
+	err := w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+		atomic.AddInt64(&sum, int64(i))
+
+		if i%2 == 1 {
+			return fmt.Errorf("%d: %w", i, errOdd)
+		}
+
+		return nil
+	})
+
+	assert.Equal(t, int64(55), sum)
+	require.ErrorIs(t, err, errOdd)
+	assert.NotErrorIs(t, err, context.Canceled)
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 5) //nolint:errorlint // testing join
+}
+
+func TestFanOutErrorFunc(t *testing.T) {
+	var (
+		m      sync.Mutex
+		failed []int
+	)
+
+	w := worker.NewFanOut[int](2, 10).WithErrorFunc(func(i int, err error) {
+		assert.ErrorIs(t, err, errOdd)
+
+		m.Lock()
+		defer m.Unlock()
+
+		failed = append(failed, i)
+	})
+
+	for _, i := range testInts(5) {
+		w.Invoke(i)
+	}
+
+	w.Close()
+
+	err := w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+		if i%2 == 1 {
+			return errOdd
+		}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	sort.Ints(failed)
+	assert.Equal(t, []int{1, 3, 5}, failed)
+}
+
+func TestFanOutProcessContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := worker.NewFanOut[int](1, 0)
+
+	started := make(chan struct{})
+	invokeErr := make(chan error)
+
+	go func() {
+		assert.NoError(t, w.InvokeContext(context.Background(), 1))
+		close(started)
+
+		// Blocks, the single worker is busy.
+		invokeErr <- w.InvokeContext(context.Background(), 2)
+	}()
+
+	processed := int64(0)
+	err := w.ProcessContext(ctx, func(ctx context.Context, i int) error {
+		atomic.AddInt64(&processed, 1)
+		<-started
+		cancel()
+		<-ctx.Done()
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, <-invokeErr, worker.ErrShutdown)
+	assert.Equal(t, int64(1), processed)
+
+	// Producer ctx
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, worker.NewFanOut[int](1, 0).InvokeContext(ctx, 1), context.Canceled)
+}
+
+func TestFanOutBackpressure(t *testing.T) {
+	w := worker.NewFanOut[int](1, 2)
+
+	assert.True(t, w.TryInvoke(1))
+	assert.True(t, w.TryInvoke(2))
+	assert.Equal(t, 2, w.QueueDepth())
+	assert.False(t, w.TryInvoke(3), "buffer full")
+
+	require.ErrorIs(t, w.InvokeTimeout(3, time.Millisecond), context.DeadlineExceeded)
+
+	blocked := make(chan error)
+
+	go func() {
+		blocked <- w.Invoke(3)
+	}()
+
+	w.Close()
+	w.Close() // Idempotent
+
+	require.ErrorIs(t, <-blocked, worker.ErrClosed, "blocked Invoke released by Close")
+	require.ErrorIs(t, w.Invoke(4), worker.ErrClosed)
+	require.ErrorIs(t, w.InvokeContext(context.Background(), 4), worker.ErrClosed)
+	assert.False(t, w.TryInvoke(4))
+
+	var got []int
+
+	w.Process(func(i int) {
+		got = append(got, i)
+	})
+
+	assert.Equal(t, []int{1, 2}, got, "buffered inputs processed after Close")
+	assert.Equal(t, 0, w.QueueDepth())
+}
+
+func TestFanOutResize(t *testing.T) {
+	w := worker.NewFanOut[int](1, 100)
+	assert.Equal(t, uint(1), w.WorkerCount())
+
+	release := make(chan struct{})
+	sum := int64(0)
+	done := make(chan error)
+
+	for _, i := range testInts(20) {
+		require.NoError(t, w.Invoke(i))
+	}
+
+	go func() {
+		done <- w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+			<-release
+			atomic.AddInt64(&sum, int64(i))
+
+			return nil
+		})
+	}()
+
+	assert.Eventually(t, func() bool { return w.Stats().Busy == 1 }, time.Second, time.Millisecond)
+
+	w.Resize(4)
+	assert.Equal(t, uint(4), w.WorkerCount())
+	assert.Eventually(t, func() bool { return w.Stats().Busy == 4 }, time.Second, time.Millisecond)
+
+	w.Resize(0)
+	assert.Equal(t, uint(1), w.WorkerCount(), "minimum 1")
+
+	close(release)
+
+	// Resizing down never drops in-flight inputs, and Process waits for Close.
+	assert.Eventually(t, func() bool { return w.Stats().Processed == 20 }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return w.Stats().Idle == 1 }, time.Second, time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("returned before Close")
+	default:
+	}
+
+	w.Close()
+	require.NoError(t, <-done)
+	assert.Equal(t, int64(210), sum)
+
+	w.Resize(3)
+	assert.Equal(t, uint(3), w.WorkerCount(), "resized after processing")
+}
+
+func TestFanOutResizeConcurrent(t *testing.T) {
+	w := worker.NewFanOut[int](2, 0)
+	sum := int64(0)
+	done := make(chan error)
+
+	go func() {
+		done <- w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+			atomic.AddInt64(&sum, int64(i))
+
+			return nil
+		})
+	}()
+
+	go func() {
+		for _, i := range testInts(1000) {
+			assert.NoError(t, w.Invoke(i))
+		}
+
+		w.Close()
+	}()
+
+	for i := range 200 {
+		w.Resize(uint(i%7 + 1))
+	}
+
+	require.NoError(t, <-done)
+	assert.Equal(t, int64(500500), sum)
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// gate coordinates sends with Close and processing shutdown, so sends never panic on a closed channel.
+type gate struct {
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+	enqueued  atomic.Uint64
+}
+
+func newGate() *gate {
+	return &gate{closing: make(chan struct{}), done: make(chan struct{})}
+}
+
+// close wakes blocked senders, then calls closeInputs once no send is in progress.
+func (g *gate) close(closeInputs func()) {
+	g.closeOnce.Do(func() {
+		close(g.closing)
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.closed = true
+
+		closeInputs()
+	})
+}
+
+// shutdown stops accepting inputs, because processing has been cancelled.
+func (g *gate) shutdown() {
+	g.doneOnce.Do(func() {
+		close(g.done)
+	})
+}
+
+// send adds the input to the channel, blocking until accepted, the ctx is done, or the gate is closed or shutdown.
+func send[I any](ctx context.Context, g *gate, c chan<- I, input I) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+
+	// Check first, select does not prioritize between ready cases.
+	select {
+	case <-g.done:
+		return ErrShutdown
+	default:
+	}
+
+	select {
+	case c <- input:
+		g.enqueued.Add(1)
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-g.closing:
+		return ErrClosed
+	case <-g.done:
+		return ErrShutdown
+	}
+}
+
+// sendTimeout is send with a timeout, returning context.DeadlineExceeded if the input is not accepted in time.
+func sendTimeout[I any](g *gate, c chan<- I, input I, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return send(ctx, g, c, input)
+}
+
+// trySend adds the input to the channel if it can be accepted immediately.
+func trySend[I any](g *gate, c chan<- I, input I) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.closed {
+		return false
+	}
+
+	select {
+	case <-g.done:
+		return false
+	default:
+	}
+
+	select {
+	case c <- input:
+		g.enqueued.Add(1)
+
+		return true
+	default:
+		return false
+	}
+}
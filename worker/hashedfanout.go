@@ -1,8 +1,13 @@
 package worker
 
 import (
+	"context"
+	"encoding/binary"
 	"hash/maphash"
-	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 )
 
 // HashFunc converts an input to a uint value.  It must be deterministic.  See examples below.
@@ -14,68 +19,237 @@ type HashedFanOut[I any] struct {
 	workerCount uint
 	inputs      []chan I
 	hasher      HashFunc[I]
+	assign      AssignFunc
+	gate        *gate
+	workers     []*counters
+	onError     ErrorFunc[I]
+	onPanic     PanicFunc[I]
 }
 
 func NewHashedFanOut[I any](workerCount, bufferSize uint, hasher HashFunc[I]) *HashedFanOut[I] {
 	inputs := make([]chan I, workerCount)
+	workers := make([]*counters, workerCount)
+
 	for i := range workerCount {
 		inputs[i] = make(chan I, bufferSize)
+		workers[i] = newCounters()
 	}
 
 	return &HashedFanOut[I]{
 		workerCount: workerCount,
 		inputs:      inputs,
 		hasher:      hasher,
+		assign:      ModuloAssign,
+		gate:        newGate(),
+		workers:     workers,
 	}
 }
 
-func (f *HashedFanOut[I]) Invoke(input I) {
-	hash := f.hasher(input)
-	f.inputs[hash%f.workerCount] <- input
+// WithErrorFunc reports processor errors to `fn` as they occur, instead of collecting them for ProcessContext.
+// Use this for long-running pools.
+func (f *HashedFanOut[I]) WithErrorFunc(fn ErrorFunc[I]) *HashedFanOut[I] {
+	f.onError = fn
+
+	return f
+}
+
+// WithPanicFunc reports panics recovered from processors to `fn`.  By default panics are logged to the ctx logger
+// with LogPanic and handled as processor errors (PanicError).  The worker continues with the next input either way.
+func (f *HashedFanOut[I]) WithPanicFunc(fn PanicFunc[I]) *HashedFanOut[I] {
+	f.onPanic = fn
+
+	return f
+}
+
+// WithAssignFunc sets how hashes are assigned to workers, the default is ModuloAssign.  Set it before adding inputs.
+func (f *HashedFanOut[I]) WithAssignFunc(fn AssignFunc) *HashedFanOut[I] {
+	f.assign = fn
+
+	return f
 }
 
+// Close closes the input channels, see FanOut.Close.
 func (f *HashedFanOut[I]) Close() {
-	for _, c := range f.inputs {
-		close(c)
+	f.gate.close(func() {
+		for _, c := range f.inputs {
+			close(c)
+		}
+	})
+}
+
+// Invoke adds the data to the keyed worker for processing, see FanOut.Invoke.
+func (f *HashedFanOut[I]) Invoke(input I) error {
+	return send(context.Background(), f.gate, f.inputFor(input), input)
+}
+
+// InvokeTimeout adds the data to the keyed worker for processing, see FanOut.InvokeTimeout.
+func (f *HashedFanOut[I]) InvokeTimeout(input I, timeout time.Duration) error {
+	return sendTimeout(f.gate, f.inputFor(input), input, timeout)
+}
+
+// TryInvoke adds the data to the keyed worker for processing if it can be accepted immediately, see
+// FanOut.TryInvoke.
+func (f *HashedFanOut[I]) TryInvoke(input I) bool {
+	return trySend(f.gate, f.inputFor(input), input)
+}
+
+// QueueDepth returns the number of inputs waiting for the worker the input hashes to.
+func (f *HashedFanOut[I]) QueueDepth(input I) int {
+	return len(f.inputFor(input))
+}
+
+// QueueDepths returns the number of inputs waiting for each worker.
+func (f *HashedFanOut[I]) QueueDepths() []int {
+	out := make([]int, len(f.inputs))
+	for i, c := range f.inputs {
+		out[i] = len(c)
 	}
+
+	return out
+}
+
+// WorkerFor returns the index of the worker the input is assigned to, matching the Stats Workers index.
+func (f *HashedFanOut[I]) WorkerFor(input I) int {
+	return int(f.assign(f.hasher(input), f.workerCount))
+}
+
+func (f *HashedFanOut[I]) inputFor(input I) chan I {
+	return f.inputs[f.WorkerFor(input)]
 }
 
 func (f *HashedFanOut[I]) Process(p ProcessorFunc[I]) {
-	wg := sync.WaitGroup{}
+	process(f.ProcessContext, p)
+}
 
-	for _, inputChan := range f.inputs {
-		wg.Add(1)
+// InvokeContext adds the data to the keyed worker for processing, see FanOut.InvokeContext.
+func (f *HashedFanOut[I]) InvokeContext(ctx context.Context, input I) error {
+	return send(ctx, f.gate, f.inputFor(input), input)
+}
 
-		go func(c chan I) {
-			for i := range c {
-				p(i)
-			}
+// ProcessContext handles all inputs until the input channels are closed or the ctx is done.  See
+// FanOut.ProcessContext for cancellation and error handling.
+func (f *HashedFanOut[I]) ProcessContext(ctx context.Context, p ContextProcessorFunc[I]) error {
+	errs := &errorCollector[I]{onError: f.onError, onPanic: f.onPanic}
+
+	return processWorkers(ctx, f.gate.shutdown, errs, func(spawn func(func() bool)) {
+		for i, c := range f.inputs {
+			spawn(func() bool {
+				return work(ctx, c, nil, p, errs, f.workers[i]) == exitCancelled
+			})
+		}
+	})
+}
 
-			wg.Done()
-		}(inputChan)
+// Stats returns a snapshot of the pool usage, including per worker stats for spotting hot keys.
+func (f *HashedFanOut[I]) Stats() Stats {
+	s := Stats{
+		Enqueued: f.gate.enqueued.Load(),
+		Queued:   f.QueueDepths(),
+		Workers:  make([]WorkerStats, len(f.workers)),
 	}
 
-	wg.Wait()
+	for i, c := range f.workers {
+		w := WorkerStats{
+			Processed: c.processed.Load(),
+			Failed:    c.failed.Load(),
+			Busy:      c.busy.Load() > 0,
+			Queued:    s.Queued[i],
+			Duration:  c.duration.snapshot(),
+		}
+
+		s.Workers[i] = w
+		s.Processed += w.Processed
+		s.Failed += w.Failed
+		s.Duration.add(w.Duration)
+
+		switch {
+		case w.Busy:
+			s.Busy++
+		case c.running.Load() > 0:
+			s.Idle++
+		}
+	}
+
+	return s
+}
+
+// Log logs a summary of the pool statistics, with processed counts per worker.
+func (f *HashedFanOut[I]) Log(l zerolog.Logger) {
+	f.Stats().log(l)
+}
+
+// LogEvery logs a summary of the pool statistics every `interval` until the ctx is done.
+// This blocks, it is intended to be run in a separate go routine.
+func (f *HashedFanOut[I]) LogEvery(ctx context.Context, l zerolog.Logger, interval time.Duration) {
+	logEvery(ctx, interval, func() { f.Log(l) })
 }
 
 type KeyFunc[I, K any] func(I) K
 
 // StringHasher given a KeyFunc that returns a string for a given input, returns a consistent hash for the string.
+// The hash is randomly seeded per StringHasher, it is not stable across processes.
 func StringHasher[I any](keyFunc KeyFunc[I, string]) HashFunc[I] {
-	var (
-		hash maphash.Hash
-		m    sync.Mutex
-	)
+	seed := maphash.MakeSeed()
+
+	return func(input I) uint {
+		return uint(maphash.String(seed, keyFunc(input)))
+	}
+}
+
+// Integer is the constraint for IntHasher keys.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// IntHasher given a KeyFunc that returns an integer for a given input, returns a well mixed hash for the integer.
+// The hash is stable across processes.
+func IntHasher[I any, K Integer](keyFunc KeyFunc[I, K]) HashFunc[I] {
+	return func(input I) uint {
+		return uint(mix64(uint64(keyFunc(input))))
+	}
+}
 
+// UUIDHasher given a KeyFunc that returns a uuid.UUID for a given input, returns a hash for the UUID.
+// The hash is stable across processes.
+func UUIDHasher[I any](keyFunc KeyFunc[I, uuid.UUID]) HashFunc[I] {
 	return func(input I) uint {
-		k := keyFunc(input)
+		id := keyFunc(input)
 
-		m.Lock()
-		defer m.Unlock()
+		return uint(mix64(binary.BigEndian.Uint64(id[:8]) ^ binary.BigEndian.Uint64(id[8:])))
+	}
+}
+
+// mix64 is the splitmix64 finalizer, spreading sequential keys across the full 64 bits.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
 
-		hash.Reset()
-		_, _ = hash.WriteString(k)
+	return x
+}
 
-		return uint(hash.Sum64())
+// AssignFunc maps a hash to a worker index in [0, workerCount).
+type AssignFunc func(hash, workerCount uint) uint
+
+// ModuloAssign is the default AssignFunc, hash % workerCount.  Changing the worker count remaps almost every key.
+func ModuloAssign(hash, workerCount uint) uint {
+	return hash % workerCount
+}
+
+// JumpAssign is a consistent AssignFunc using jump consistent hashing (Lamping & Veach).  Changing the worker count
+// from n to n+1 remaps only 1/(n+1) of the keys, so key assignments are mostly stable across deployments.
+func JumpAssign(hash, workerCount uint) uint {
+	key := uint64(hash)
+
+	var b, j int64 = -1, 0
+
+	for j < int64(workerCount) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
 	}
+
+	return uint(b)
 }
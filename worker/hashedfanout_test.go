@@ -1,9 +1,15 @@
 package worker_test
 
 import (
+	"context"
 	"strconv"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/bir/iken/worker"
 )
@@ -79,3 +85,166 @@ func TestNewHashedFanOut(t *testing.T) {
 		})
 	}
 }
+
+func TestHashedFanOutProcessContext(t *testing.T) {
+	hasher := worker.StringHasher(strconv.Itoa)
+
+	w := worker.NewHashedFanOut[int](4, 0, hasher)
+
+	go func() {
+		for _, i := range testInts(10) {
+			assert.NoError(t, w.InvokeContext(context.Background(), i))
+		}
+
+		w.Close()
+	}()
+
+	sum := int64(0)
+	err := w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+		atomic.AddInt64(&sum, int64(i))
+
+		if i > 8 {
+			return errOdd
+		}
+
+		return nil
+	})
+
+	assert.Equal(t, int64(55), sum)
+	require.ErrorIs(t, err, errOdd)
+	assert.NotErrorIs(t, err, context.Canceled)
+}
+
+func TestHashedFanOutProcessContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := worker.NewHashedFanOut[int](1, 0, worker.StringHasher(strconv.Itoa))
+
+	started := make(chan struct{})
+	invokeErr := make(chan error)
+
+	go func() {
+		assert.NoError(t, w.InvokeContext(context.Background(), 1))
+		close(started)
+
+		invokeErr <- w.InvokeContext(context.Background(), 2)
+	}()
+
+	err := w.ProcessContext(ctx, func(ctx context.Context, _ int) error {
+		<-started
+		cancel()
+		<-ctx.Done()
+
+		return nil
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, <-invokeErr, worker.ErrShutdown)
+}
+
+func TestHashedFanOutBackpressure(t *testing.T) {
+	w := worker.NewHashedFanOut[int](2, 1, func(i int) uint { return uint(i) })
+
+	assert.True(t, w.TryInvoke(2))
+	assert.False(t, w.TryInvoke(4), "worker 0 full")
+	assert.True(t, w.TryInvoke(1), "worker 1 has room")
+	assert.Equal(t, []int{1, 1}, w.QueueDepths())
+	assert.Equal(t, 1, w.QueueDepth(6))
+
+	require.ErrorIs(t, w.InvokeTimeout(4, time.Millisecond), context.DeadlineExceeded)
+
+	w.Close()
+
+	require.ErrorIs(t, w.Invoke(3), worker.ErrClosed)
+
+	sum := int64(0)
+	w.Process(func(i int) {
+		atomic.AddInt64(&sum, int64(i))
+	})
+
+	assert.Equal(t, int64(3), sum)
+}
+
+func TestJumpAssign(t *testing.T) {
+	hasher := worker.IntHasher(func(i int) int { return i })
+
+	const keys = 10000
+
+	for n := uint(1); n < 20; n++ {
+		moved := 0
+
+		for k := range keys {
+			h := hasher(k)
+			before := worker.JumpAssign(h, n)
+			after := worker.JumpAssign(h, n+1)
+
+			require.Less(t, before, n)
+
+			if before != after {
+				moved++
+
+				require.Equal(t, n, after, "keys only move to the new worker")
+			}
+		}
+
+		// Expect keys/(n+1) moved, allow for variance.
+		expected := keys / int(n+1)
+		assert.InDelta(t, expected, moved, float64(expected)*0.2+50, "n=%d", n)
+	}
+
+	assert.Equal(t, uint(7), worker.ModuloAssign(17, 10))
+}
+
+func TestHashers(t *testing.T) {
+	ints := worker.IntHasher(func(i int32) int32 { return i })
+	assert.Equal(t, ints(42), ints(42))
+	assert.NotEqual(t, ints(42), ints(43))
+
+	ids := worker.UUIDHasher(func(id uuid.UUID) uuid.UUID { return id })
+	id := uuid.New()
+	assert.Equal(t, ids(id), ids(id))
+	assert.NotEqual(t, ids(id), ids(uuid.New()))
+
+	strs := worker.StringHasher(func(s string) string { return s })
+	assert.Equal(t, strs("a"), strs("a"))
+	assert.NotEqual(t, strs("a"), strs("b"))
+
+	// Sequential keys are spread evenly
+	counts := make([]int, 8)
+	for i := range 8000 {
+		counts[worker.JumpAssign(ints(int32(i)), 8)]++
+	}
+
+	for _, c := range counts {
+		assert.InDelta(t, 1000, c, 150)
+	}
+}
+
+func TestHashedFanOutAssignFunc(t *testing.T) {
+	w := worker.NewHashedFanOut[int](4, 10, worker.IntHasher(func(i int) int { return i })).
+		WithAssignFunc(func(uint, uint) uint { return 2 })
+
+	assert.Equal(t, 2, w.WorkerFor(1))
+
+	for _, i := range testInts(5) {
+		require.NoError(t, w.Invoke(i))
+	}
+
+	assert.Equal(t, []int{0, 0, 5, 0}, w.QueueDepths())
+
+	w.Close()
+	w.Process(func(int) {})
+
+	assert.Equal(t, uint64(5), w.Stats().Workers[2].Processed)
+}
+
+func BenchmarkStringHasher(b *testing.B) {
+	hasher := worker.StringHasher(strconv.Itoa)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_ = hasher(i)
+			i++
+		}
+	})
+}
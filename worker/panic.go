@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/bir/iken/httplog"
+	"github.com/bir/iken/httputil"
+)
+
+// panicStackSkip skips debug.Stack, newPanicError, the deferred recover and runtime.gopanic.
+const panicStackSkip = 4
+
+// PanicError is a panic recovered from a processor.
+type PanicError struct {
+	Value any
+	Stack []string
+}
+
+func newPanicError(value any) *PanicError {
+	return &PanicError{Value: value, Stack: httplog.SimplifyStack(string(debug.Stack()), panicStackSkip)}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("worker: panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+
+	return err
+}
+
+// PanicFunc receives panics recovered from processors.
+type PanicFunc[I any] func(ctx context.Context, input I, err *PanicError)
+
+// LogPanic is a PanicFunc that logs the panic and stack to the ctx logger.  If the ctx has no logger and
+// zerolog.DefaultContextLogger is not set, the global log.Logger is used so panics are never silently dropped.
+func LogPanic[I any](ctx context.Context, _ I, err *PanicError) {
+	l := zerolog.Ctx(ctx)
+	if zerolog.DefaultContextLogger == nil && l == zerolog.Ctx(context.Background()) {
+		l = &log.Logger
+	}
+
+	l.Error().Err(err).Strs(httputil.LogStack, err.Stack).Msg("Panic")
+}
+
+// safeProcess calls the processor, converting panics to a PanicError.
+func safeProcess[I any](ctx context.Context, p ContextProcessorFunc[I], input I) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+	}()
+
+	return p(ctx, input)
+}
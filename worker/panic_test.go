@@ -0,0 +1,156 @@
+package worker_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+var errBoom = errors.New("boom")
+
+func panicky(_ context.Context, i int) error {
+	switch i {
+	case 2:
+		panic("two")
+	case 4:
+		panic(errBoom)
+	}
+
+	return nil
+}
+
+func TestFanOutPanic(t *testing.T) {
+	// Single worker, it must survive both panics.
+	w := worker.NewFanOut[int](1, 5)
+	for _, i := range testInts(5) {
+		w.Invoke(i)
+	}
+
+	w.Close()
+
+	err := w.ProcessContext(context.Background(), panicky)
+	require.Error(t, err)
+	require.ErrorIs(t, err, errBoom)
+
+	var panicErr *worker.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "two", panicErr.Value)
+	assert.Equal(t, "worker: panic: two", panicErr.Error())
+	require.NotEmpty(t, panicErr.Stack)
+	assert.Contains(t, panicErr.Stack[0], "panicky", "stack starts at the panic")
+}
+
+func TestHashedFanOutPanicFunc(t *testing.T) {
+	var (
+		m        sync.Mutex
+		panicked []int
+	)
+
+	w := worker.NewHashedFanOut[int](2, 5, worker.StringHasher(strconv.Itoa)).
+		WithPanicFunc(func(_ context.Context, i int, err *worker.PanicError) {
+			m.Lock()
+			defer m.Unlock()
+
+			panicked = append(panicked, i)
+		})
+
+	for _, i := range testInts(5) {
+		w.Invoke(i)
+	}
+
+	w.Close()
+
+	processed := int64(0)
+	err := w.ProcessContext(context.Background(), func(ctx context.Context, i int) error {
+		atomic.AddInt64(&processed, 1)
+
+		return panicky(ctx, i)
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), processed)
+	assert.ElementsMatch(t, []int{2, 4}, panicked)
+}
+
+func TestProcessPanic(t *testing.T) {
+	w := worker.NewFanOut[int](1, 5)
+	for _, i := range testInts(5) {
+		w.Invoke(i)
+	}
+
+	w.Close()
+
+	sum := 0
+
+	assert.NotPanics(t, func() {
+		w.Process(func(i int) {
+			sum += i
+			_ = panicky(context.Background(), i)
+		})
+	})
+	assert.Equal(t, 15, sum)
+}
+
+func TestLogPanic(t *testing.T) {
+	var buf bytes.Buffer
+
+	ctx := zerolog.New(&buf).WithContext(context.Background())
+
+	w := worker.NewFanOut[int](1, 1).WithPanicFunc(worker.LogPanic[int])
+	w.Invoke(2)
+	w.Close()
+
+	require.NoError(t, w.ProcessContext(ctx, panicky))
+
+	out := buf.String()
+	assert.True(t, strings.Contains(out, `"error":"worker: panic: two"`), out)
+	assert.True(t, strings.Contains(out, `"error.stack":[`), out)
+}
+
+func TestPanicLoggedByDefault(t *testing.T) {
+	var buf bytes.Buffer
+
+	ctx := zerolog.New(&buf).WithContext(context.Background())
+
+	w := worker.NewFanOut[int](1, 1)
+	w.Invoke(2)
+	w.Close()
+
+	err := w.ProcessContext(ctx, panicky)
+
+	var panicErr *worker.PanicError
+
+	require.ErrorAs(t, err, &panicErr)
+	assert.Contains(t, buf.String(), `"error":"worker: panic: two"`)
+}
+
+func TestProcessPanicLogged(t *testing.T) {
+	var buf bytes.Buffer
+
+	// Process has no ctx logger, the global logger is used.
+	global := log.Logger
+	log.Logger = zerolog.New(&buf)
+
+	t.Cleanup(func() { log.Logger = global })
+
+	w := worker.NewFanOut[int](1, 1)
+	w.Invoke(2)
+	w.Close()
+
+	w.Process(func(i int) { _ = panicky(context.Background(), i) })
+
+	assert.Contains(t, buf.String(), `"error":"worker: panic: two"`)
+	assert.Contains(t, buf.String(), `"error.stack":[`)
+}
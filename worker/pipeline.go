@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// Pipeline connects typed stages built from FanOut pools.  Closing the Source drains each stage in turn, and the
+// first stage error cancels the whole pipeline.
+//
+//	p := worker.NewPipeline(ctx)
+//	src := worker.NewSource[string](p, 10)
+//	parsed := worker.Map(src.Stream, 4, 10, parse)
+//	worker.Drain(parsed, 2, store)
+//
+//	for _, line := range lines {
+//		if err := src.Invoke(line); err != nil {
+//			break
+//		}
+//	}
+//
+//	src.Close()
+//
+//	err := p.Wait()
+type Pipeline struct {
+	ctx    context.Context //nolint:containedctx // shared by all stages
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+}
+
+// NewPipeline creates a pipeline, cancelling the ctx stops all stages.
+func NewPipeline(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Wait blocks until every stage has stopped, returning the first stage error or the ctx cause if cancelled.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+
+	err := context.Cause(p.ctx)
+
+	p.cancel(nil)
+
+	return err
+}
+
+func (p *Pipeline) start(run func()) {
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		run()
+	}()
+}
+
+// Stream is a typed connection between stages.  Every Stream must be consumed by exactly one Map or Drain, otherwise
+// the producing stage blocks once the buffer is full.
+type Stream[T any] struct {
+	p      *Pipeline
+	fanOut *FanOut[T]
+}
+
+func newStream[T any](p *Pipeline, bufferSize uint) Stream[T] {
+	f := NewFanOut[T](1, bufferSize).WithErrorFunc(func(_ T, err error) {
+		p.cancel(err)
+	})
+
+	return Stream[T]{p: p, fanOut: f}
+}
+
+// consume processes the stream with `workers` workers, then calls closeNext once drained.
+func (s Stream[T]) consume(workers uint, fn ContextProcessorFunc[T], closeNext func()) {
+	s.fanOut.Resize(workers)
+
+	s.p.start(func() {
+		defer closeNext()
+
+		// Errors are reported to the ErrorFunc as they occur, cancelling the pipeline.
+		_ = s.fanOut.ProcessContext(s.p.ctx, fn)
+	})
+}
+
+// Source is the input of a pipeline.
+type Source[T any] struct {
+	Stream[T]
+}
+
+// NewSource creates the pipeline input, buffering up to `bufferSize` inputs.
+func NewSource[T any](p *Pipeline, bufferSize uint) *Source[T] {
+	return &Source[T]{newStream[T](p, bufferSize)}
+}
+
+// Invoke adds the input to the pipeline, blocking until accepted.  Returns an error once the pipeline is cancelled
+// or the source is closed.
+func (s *Source[T]) Invoke(input T) error {
+	return s.fanOut.InvokeContext(s.p.ctx, input)
+}
+
+// Close closes the pipeline input, each stage is drained and closed in turn.
+func (s *Source[T]) Close() {
+	s.fanOut.Close()
+}
+
+// Map adds a stage processing `in` with `workers` workers, sending each result to the returned stream, buffered up
+// to `bufferSize`.  An error cancels the pipeline.
+func Map[I, O any](in Stream[I], workers, bufferSize uint, fn func(context.Context, I) (O, error)) Stream[O] {
+	out := newStream[O](in.p, bufferSize)
+
+	in.consume(workers, func(ctx context.Context, input I) error {
+		o, err := fn(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		return out.fanOut.InvokeContext(ctx, o)
+	}, out.fanOut.Close)
+
+	return out
+}
+
+// Drain adds the final stage processing `in` with `workers` workers.  An error cancels the pipeline.
+func Drain[T any](in Stream[T], workers uint, fn ContextProcessorFunc[T]) {
+	in.consume(workers, fn, func() {})
+}
@@ -0,0 +1,141 @@
+package worker_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+func TestPipeline(t *testing.T) {
+	p := worker.NewPipeline(context.Background())
+	src := worker.NewSource[int](p, 10)
+
+	squares := worker.Map(src.Stream, 4, 5, func(_ context.Context, i int) (int, error) {
+		return i * i, nil
+	})
+
+	strs := worker.Map(squares, 2, 0, func(_ context.Context, i int) (string, error) {
+		return strconv.Itoa(i), nil
+	})
+
+	var (
+		m   sync.Mutex
+		out []string
+	)
+
+	worker.Drain(strs, 3, func(_ context.Context, s string) error {
+		m.Lock()
+		defer m.Unlock()
+
+		out = append(out, s)
+
+		return nil
+	})
+
+	for _, i := range testInts(100) {
+		require.NoError(t, src.Invoke(i))
+	}
+
+	src.Close()
+
+	require.NoError(t, p.Wait())
+	assert.Len(t, out, 100, "every stage drained before Wait returns")
+	assert.Contains(t, out, "10000")
+}
+
+func TestPipelineError(t *testing.T) {
+	p := worker.NewPipeline(context.Background())
+	src := worker.NewSource[int](p, 0)
+
+	evens := worker.Map(src.Stream, 2, 0, func(_ context.Context, i int) (int, error) {
+		if i == 50 {
+			return 0, errBoom
+		}
+
+		return i, nil
+	})
+
+	worker.Drain(evens, 1, func(context.Context, int) error { return nil })
+
+	var err error
+
+	for i := 1; err == nil; i++ {
+		err = src.Invoke(i)
+	}
+
+	src.Close()
+
+	require.ErrorIs(t, p.Wait(), errBoom)
+	assert.Error(t, err, "source stopped by cancellation")
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := worker.NewPipeline(ctx)
+	src := worker.NewSource[int](p, 0)
+
+	worker.Drain(worker.Map(src.Stream, 1, 0, func(ctx context.Context, i int) (int, error) {
+		if i == 3 {
+			cancel()
+		}
+
+		return i, nil
+	}), 1, func(context.Context, int) error { return nil })
+
+	var err error
+
+	for i := 1; err == nil; i++ {
+		err = src.Invoke(i)
+	}
+
+	require.ErrorIs(t, p.Wait(), context.Canceled)
+}
+
+func ExampleNewPipeline() {
+	p := worker.NewPipeline(context.Background())
+	src := worker.NewSource[string](p, 10)
+
+	lengths := worker.Map(src.Stream, 4, 10, func(_ context.Context, s string) (string, error) {
+		return fmt.Sprintf("%s=%d", s, len(s)), nil
+	})
+
+	var (
+		m   sync.Mutex
+		out []string
+	)
+
+	worker.Drain(lengths, 1, func(_ context.Context, s string) error {
+		m.Lock()
+		defer m.Unlock()
+
+		out = append(out, s)
+
+		return nil
+	})
+
+	for _, s := range []string{"A", "BBBB", "CC"} {
+		if err := src.Invoke(s); err != nil {
+			break
+		}
+	}
+
+	src.Close()
+
+	if err := p.Wait(); err != nil {
+		fmt.Println(err)
+	}
+
+	sort.Strings(out)
+	fmt.Println(out)
+
+	// Output:
+	// [A=1 BBBB=4 CC=2]
+}
@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"context"
+)
+
+// PriorityFanOut processes inputs by priority level, 0 is the highest.  Lower levels are only processed when higher
+// levels are empty, unless the starvation limit is reached.
+type PriorityFanOut[I any] struct {
+	scheduled[I]
+
+	policy *priorityPolicy[I]
+}
+
+// NewPriorityFanOut creates a pool with `levels` priority levels, buffering up to `bufferSize` inputs per level, so a
+// full lower level never blocks higher levels.  The default starvation limit is 10, see WithStarvationLimit.
+func NewPriorityFanOut[I any](workerCount, levels, bufferSize uint) *PriorityFanOut[I] {
+	levels = max(levels, 1)
+	policy := &priorityPolicy[I]{
+		levels:   make([][]I, levels),
+		starved:  make([]uint, levels),
+		limit:    DefaultStarvationLimit,
+		capacity: int(max(bufferSize, 1)),
+	}
+
+	return &PriorityFanOut[I]{
+		scheduled: scheduled[I]{workerCount: workerCount, queue: newSchedQueue[I](policy)},
+		policy:    policy,
+	}
+}
+
+// DefaultStarvationLimit is the starvation limit used by NewPriorityFanOut.
+const DefaultStarvationLimit = 10
+
+// WithStarvationLimit sets how many inputs may be taken from higher levels while a lower level is waiting.  Once
+// reached, the waiting level is processed next.  0 disables starvation protection.  Set it before adding inputs.
+func (f *PriorityFanOut[I]) WithStarvationLimit(n uint) *PriorityFanOut[I] {
+	f.policy.limit = n
+
+	return f
+}
+
+// WithErrorFunc reports processor errors to `fn`, see FanOut.WithErrorFunc.
+func (f *PriorityFanOut[I]) WithErrorFunc(fn ErrorFunc[I]) *PriorityFanOut[I] {
+	f.onError = fn
+
+	return f
+}
+
+// WithPanicFunc reports panics recovered from processors to `fn`, see FanOut.WithPanicFunc.
+func (f *PriorityFanOut[I]) WithPanicFunc(fn PanicFunc[I]) *PriorityFanOut[I] {
+	f.onPanic = fn
+
+	return f
+}
+
+// Invoke adds the data at the priority level, blocking until accepted.  Levels beyond the last are treated as the
+// last (lowest).  See FanOut.Invoke.
+func (f *PriorityFanOut[I]) Invoke(input I, priority uint) error {
+	_, err := f.queue.push(context.Background(), input, priority, true)
+
+	return err
+}
+
+// InvokeContext adds the data at the priority level, see Invoke and FanOut.InvokeContext.
+func (f *PriorityFanOut[I]) InvokeContext(ctx context.Context, input I, priority uint) error {
+	_, err := f.queue.push(ctx, input, priority, true)
+
+	return err
+}
+
+// TryInvoke adds the data at the priority level if the level's buffer has room.  Returns false if the input was not
+// accepted.
+func (f *PriorityFanOut[I]) TryInvoke(input I, priority uint) bool {
+	ok, _ := f.queue.push(context.Background(), input, priority, false)
+
+	return ok
+}
+
+// QueueDepths returns the number of inputs waiting at each level.
+func (f *PriorityFanOut[I]) QueueDepths() []int {
+	f.queue.mu.Lock()
+	defer f.queue.mu.Unlock()
+
+	out := make([]int, len(f.policy.levels))
+	for i, l := range f.policy.levels {
+		out[i] = len(l)
+	}
+
+	return out
+}
+
+// priorityPolicy takes from the highest non-empty level.  starved counts the inputs taken ahead of each waiting level,
+// capacity is per level.
+type priorityPolicy[I any] struct {
+	levels   [][]I
+	starved  []uint
+	limit    uint
+	capacity int
+	size     int
+}
+
+func (p *priorityPolicy[I]) level(level uint) uint {
+	return min(level, uint(len(p.levels)-1))
+}
+
+func (p *priorityPolicy[I]) push(input I, level uint) {
+	level = p.level(level)
+	p.levels[level] = append(p.levels[level], input)
+	p.size++
+}
+
+func (p *priorityPolicy[I]) pop() I {
+	highest := 0
+	for len(p.levels[highest]) == 0 {
+		highest++
+	}
+
+	chosen := highest
+
+	if p.limit > 0 {
+		for l := highest + 1; l < len(p.levels); l++ {
+			if len(p.levels[l]) > 0 && p.starved[l] >= p.limit {
+				chosen = l
+
+				break
+			}
+		}
+	}
+
+	for l := chosen + 1; l < len(p.levels); l++ {
+		if len(p.levels[l]) > 0 {
+			p.starved[l]++
+		}
+	}
+
+	p.starved[chosen] = 0
+
+	var zero I
+
+	input := p.levels[chosen][0]
+	p.levels[chosen][0] = zero
+	p.levels[chosen] = p.levels[chosen][1:]
+	p.size--
+
+	return input
+}
+
+func (p *priorityPolicy[I]) len() int {
+	return p.size
+}
+
+func (p *priorityPolicy[I]) full(_ I, level uint) bool {
+	return len(p.levels[p.level(level)]) >= p.capacity
+}
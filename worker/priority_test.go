@@ -0,0 +1,117 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+type prioritized struct {
+	level uint
+	n     int
+}
+
+func runPriority(t *testing.T, w *worker.PriorityFanOut[prioritized], inputs []prioritized) []prioritized {
+	t.Helper()
+
+	for _, in := range inputs {
+		require.NoError(t, w.Invoke(in, in.level))
+	}
+
+	w.Close()
+
+	var out []prioritized
+
+	w.Process(func(in prioritized) {
+		out = append(out, in)
+	})
+
+	return out
+}
+
+func TestPriorityFanOut(t *testing.T) {
+	w := worker.NewPriorityFanOut[prioritized](1, 3, 10).WithStarvationLimit(0)
+
+	out := runPriority(t, w, []prioritized{{2, 1}, {1, 1}, {0, 1}, {2, 2}, {0, 2}, {1, 2}, {9, 3}})
+
+	assert.Equal(t, []prioritized{{0, 1}, {0, 2}, {1, 1}, {1, 2}, {2, 1}, {2, 2}, {9, 3}}, out,
+		"by level, in order within a level, unknown levels are lowest")
+}
+
+func TestPriorityFanOutStarvation(t *testing.T) {
+	w := worker.NewPriorityFanOut[prioritized](1, 2, 10).WithStarvationLimit(2)
+
+	inputs := []prioritized{{1, 1}, {1, 2}}
+	for i := range 6 {
+		inputs = append(inputs, prioritized{0, i + 1})
+	}
+
+	assert.Equal(t, []int{0, 0}, w.QueueDepths())
+
+	out := runPriority(t, w, inputs)
+
+	assert.Equal(t, []prioritized{
+		{0, 1}, {0, 2}, {1, 1},
+		{0, 3}, {0, 4}, {1, 2},
+		{0, 5}, {0, 6},
+	}, out)
+}
+
+func TestPriorityFanOutBackpressure(t *testing.T) {
+	w := worker.NewPriorityFanOut[int](1, 2, 1)
+
+	assert.True(t, w.TryInvoke(1, 1))
+	assert.False(t, w.TryInvoke(3, 1), "level full")
+	assert.True(t, w.TryInvoke(2, 0), "a full lower level does not block higher levels")
+	assert.False(t, w.TryInvoke(3, 0), "level full")
+	assert.Equal(t, []int{1, 1}, w.QueueDepths())
+	assert.Equal(t, 2, w.QueueDepth())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan error)
+
+	go func() {
+		blocked <- w.InvokeContext(ctx, 3, 0)
+	}()
+
+	cancel()
+	require.ErrorIs(t, <-blocked, context.Canceled)
+
+	go func() {
+		blocked <- w.Invoke(3, 0)
+	}()
+
+	w.Close()
+	require.ErrorIs(t, <-blocked, worker.ErrClosed)
+	require.ErrorIs(t, w.Invoke(4, 0), worker.ErrClosed)
+
+	var out []int
+
+	require.NoError(t, w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+		out = append(out, i)
+
+		return nil
+	}))
+	assert.Equal(t, []int{2, 1}, out)
+}
+
+func TestPriorityFanOutCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := worker.NewPriorityFanOut[int](2, 2, 10).WithErrorFunc(func(int, error) {})
+
+	require.NoError(t, w.Invoke(1, 0))
+
+	err := w.ProcessContext(ctx, func(context.Context, int) error {
+		cancel()
+
+		return errOdd
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, errOdd, "reported to ErrorFunc")
+	require.ErrorIs(t, w.Invoke(2, 0), worker.ErrShutdown)
+}
@@ -0,0 +1,165 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how Retry re-attempts failed inputs.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.  Values < 1 are treated as 1.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, 0 is uncapped.
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after each attempt, values < 1 default to 2.
+	Multiplier float64
+	// Jitter randomizes each backoff by +/- this fraction (0-1) of the delay.
+	Jitter float64
+	// RetryIf reports whether an error is retryable, nil retries all errors.
+	RetryIf func(error) bool
+}
+
+// Backoff returns the delay after the given failed attempt (1 based).  Without a MaxBackoff the delay is capped at the
+// maximum time.Duration.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	// Unbounded backoff overflows for large attempts, converting out of range floats is undefined.
+	limit := float64(math.MaxInt64)
+	if p.MaxBackoff > 0 {
+		limit = float64(p.MaxBackoff)
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if d > limit {
+		d = limit
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1) //nolint:gosec // jitter does not need crypto
+	}
+
+	if d >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(d)
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	return p.RetryIf == nil || p.RetryIf(err)
+}
+
+// DeadLetter is an input that failed all attempts.
+type DeadLetter[I any] struct {
+	Input    I
+	Err      error
+	Attempts int
+}
+
+// DeadLetterSink receives inputs that failed all attempts.
+type DeadLetterSink[I any] interface {
+	Send(ctx context.Context, letter DeadLetter[I]) error
+}
+
+// Retry wraps the processor, retrying failures according to the policy.  Retries are performed inline so a
+// HashedFanOut worker blocks later inputs for the same key until the input succeeds or is dead lettered.
+//
+// Inputs that fail all attempts, or fail with a non-retryable error, are sent to the sink and the processor returns
+// nil.  If the sink is nil or the send fails the error is returned.  If the ctx is done while waiting to retry, the
+// last error is returned along with the ctx error, without dead lettering.
+// Panics are not retried, see WithPanicFunc.
+func Retry[I any](policy RetryPolicy, sink DeadLetterSink[I], p ContextProcessorFunc[I]) ContextProcessorFunc[I] {
+	maxAttempts := max(policy.MaxAttempts, 1)
+
+	return func(ctx context.Context, input I) error {
+		var (
+			err     error
+			attempt int
+		)
+
+		for attempt = 1; ; attempt++ {
+			err = p(ctx, input)
+			if err == nil {
+				return nil
+			}
+
+			if attempt >= maxAttempts || !policy.retryable(err) {
+				break
+			}
+
+			if waitErr := wait(ctx, policy.Backoff(attempt)); waitErr != nil {
+				return errors.Join(err, waitErr)
+			}
+		}
+
+		if sink == nil {
+			return err
+		}
+
+		if sendErr := sink.Send(ctx, DeadLetter[I]{Input: input, Err: err, Attempts: attempt}); sendErr != nil {
+			return errors.Join(err, sendErr)
+		}
+
+		return nil
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// MemorySink collects dead letters in memory.
+type MemorySink[I any] struct {
+	mu      sync.Mutex
+	letters []DeadLetter[I]
+}
+
+func (s *MemorySink[I]) Send(_ context.Context, letter DeadLetter[I]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters = append(s.letters, letter)
+
+	return nil
+}
+
+// Letters returns a copy of the collected dead letters, in the order received.
+func (s *MemorySink[I]) Letters() []DeadLetter[I] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]DeadLetter[I], len(s.letters))
+	copy(out, s.letters)
+
+	return out
+}
+
+// ChannelSink sends dead letters to the channel, blocking until received or the ctx is done.
+type ChannelSink[I any] chan DeadLetter[I]
+
+func (s ChannelSink[I]) Send(ctx context.Context, letter DeadLetter[I]) error {
+	select {
+	case s <- letter:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,179 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+var errFatal = errors.New("fatal")
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := worker.RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(t, time.Second, p.Backoff(1))
+	assert.Equal(t, 2*time.Second, p.Backoff(2))
+	assert.Equal(t, 4*time.Second, p.Backoff(3))
+	assert.Equal(t, 5*time.Second, p.Backoff(4))
+
+	p.Multiplier = 3
+	assert.Equal(t, 3*time.Second, p.Backoff(2))
+
+	p.Jitter = 0.5
+	for range 100 {
+		d := p.Backoff(1)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, 1500*time.Millisecond)
+	}
+
+	unbounded := worker.RetryPolicy{InitialBackoff: time.Second}
+	assert.Equal(t, time.Duration(math.MaxInt64), unbounded.Backoff(100), "clamped, not overflowed")
+	assert.Equal(t, time.Duration(math.MaxInt64), unbounded.Backoff(10000), "clamped, not overflowed")
+
+	unbounded.Jitter = 0.5
+	for range 100 {
+		assert.GreaterOrEqual(t, unbounded.Backoff(10000), time.Duration(math.MaxInt64/2))
+	}
+}
+
+// flaky fails each input `failures[input]` times.
+type flaky struct {
+	mu       sync.Mutex
+	failures map[int]int
+	calls    []int
+}
+
+func (f *flaky) process(_ context.Context, i int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, i)
+
+	if f.failures[i] < 0 {
+		return errFatal
+	}
+
+	if f.failures[i] > 0 {
+		f.failures[i]--
+
+		return errOdd
+	}
+
+	return nil
+}
+
+func TestRetry(t *testing.T) {
+	f := &flaky{failures: map[int]int{1: 2, 2: 5, 3: -1}}
+	sink := &worker.MemorySink[int]{}
+	policy := worker.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		RetryIf: func(err error) bool {
+			return !errors.Is(err, errFatal)
+		},
+	}
+
+	p := worker.Retry(policy, sink, f.process)
+
+	require.NoError(t, p(context.Background(), 1))
+	require.NoError(t, p(context.Background(), 2))
+	require.NoError(t, p(context.Background(), 3))
+	require.NoError(t, p(context.Background(), 4))
+
+	assert.Equal(t, []int{1, 1, 1, 2, 2, 2, 3, 4}, f.calls)
+
+	letters := sink.Letters()
+	require.Len(t, letters, 2)
+	assert.Equal(t, 2, letters[0].Input)
+	assert.Equal(t, 3, letters[0].Attempts)
+	require.ErrorIs(t, letters[0].Err, errOdd)
+	assert.Equal(t, 3, letters[1].Input)
+	assert.Equal(t, 1, letters[1].Attempts)
+	require.ErrorIs(t, letters[1].Err, errFatal)
+}
+
+func TestRetryNoSink(t *testing.T) {
+	f := &flaky{failures: map[int]int{1: 5}}
+	p := worker.Retry(worker.RetryPolicy{MaxAttempts: 2}, nil, f.process)
+
+	require.ErrorIs(t, p(context.Background(), 1), errOdd)
+	assert.Equal(t, []int{1, 1}, f.calls)
+}
+
+func TestRetryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &worker.MemorySink[int]{}
+
+	p := worker.Retry(worker.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}, sink,
+		func(context.Context, int) error {
+			cancel()
+
+			return errOdd
+		})
+
+	err := p(ctx, 1)
+	require.ErrorIs(t, err, errOdd)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, sink.Letters())
+}
+
+func TestChannelSink(t *testing.T) {
+	sink := make(worker.ChannelSink[int], 1)
+	p := worker.Retry(worker.RetryPolicy{}, sink, func(context.Context, int) error { return errOdd })
+
+	require.NoError(t, p(context.Background(), 7))
+
+	letter := <-sink
+	assert.Equal(t, 7, letter.Input)
+	assert.Equal(t, 1, letter.Attempts)
+
+	// Full channel, send blocks until the ctx is done.
+	sink <- letter
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := p(ctx, 8)
+	require.ErrorIs(t, err, errOdd)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetryHashedOrdering(t *testing.T) {
+	f := &flaky{failures: map[int]int{10: 2}}
+	sink := &worker.MemorySink[int]{}
+
+	// Inputs are keyed by i/10, 10 is retried and must complete before 11 - 19 for the same key.
+	w := worker.NewHashedFanOut[int](4, 20, worker.StringHasher(func(i int) string {
+		return strconv.Itoa(i / 10)
+	}))
+
+	for i := 10; i < 30; i++ {
+		w.Invoke(i)
+	}
+
+	w.Close()
+
+	err := w.ProcessContext(context.Background(),
+		worker.Retry(worker.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, sink, f.process))
+	require.NoError(t, err)
+	assert.Empty(t, sink.Letters())
+
+	var key1 []int
+
+	for _, i := range f.calls {
+		if i/10 == 1 {
+			key1 = append(key1, i)
+		}
+	}
+
+	assert.Equal(t, []int{10, 10, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}, key1)
+}
@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// schedPolicy orders the inputs of a schedQueue.  Methods are called with the queue lock held.
+type schedPolicy[I any] interface {
+	push(input I, level uint)
+	pop() I
+	len() int
+	full(input I, level uint) bool
+}
+
+// schedQueue is a bounded queue where the policy chooses the next input, used instead of channels when inputs are
+// not first in first out.
+type schedQueue[I any] struct {
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	policy   schedPolicy[I]
+	closed   bool
+	shutdown bool
+}
+
+func newSchedQueue[I any](policy schedPolicy[I]) *schedQueue[I] {
+	q := &schedQueue[I]{policy: policy}
+	q.notEmpty.L = &q.mu
+	q.notFull.L = &q.mu
+
+	return q
+}
+
+// push adds the input, waiting for room if `wait`.  Returns false if not waiting and the queue is full.
+func (q *schedQueue[I]) push(ctx context.Context, input I, level uint, wait bool) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var stop func() bool
+
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	for {
+		switch {
+		case q.closed:
+			return false, ErrClosed
+		case q.shutdown:
+			return false, ErrShutdown
+		case ctx.Err() != nil:
+			return false, ctx.Err()
+		}
+
+		if !q.policy.full(input, level) {
+			break
+		}
+
+		if !wait {
+			return false, nil
+		}
+
+		if stop == nil {
+			stop = context.AfterFunc(ctx, q.wake)
+		}
+
+		q.notFull.Wait()
+	}
+
+	q.policy.push(input, level)
+	q.notEmpty.Signal()
+
+	return true, nil
+}
+
+// pop waits for the next input.  Returns false when the queue is closed and drained, or the ctx is done.
+func (q *schedQueue[I]) pop(ctx context.Context) (I, exitReason, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		var zero I
+
+		if ctx.Err() != nil {
+			return zero, exitCancelled, false
+		}
+
+		if q.policy.len() > 0 {
+			break
+		}
+
+		if q.closed {
+			return zero, exitClosed, false
+		}
+
+		q.notEmpty.Wait()
+	}
+
+	input := q.policy.pop()
+
+	// Broadcast, the policy may track fullness per key.
+	q.notFull.Broadcast()
+
+	return input, 0, true
+}
+
+func (q *schedQueue[I]) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.policy.len()
+}
+
+func (q *schedQueue[I]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+func (q *schedQueue[I]) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.shutdown = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// wake wakes all waiters to check their ctx.
+func (q *schedQueue[I]) wake() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// scheduled is the shared implementation of the pools backed by a schedQueue.
+type scheduled[I any] struct {
+	workerCount uint
+	queue       *schedQueue[I]
+	onError     ErrorFunc[I]
+	onPanic     PanicFunc[I]
+}
+
+// Close closes the queue, blocked Invoke calls return ErrClosed.  Inputs added after Close return ErrClosed.
+func (s *scheduled[I]) Close() {
+	s.queue.close()
+}
+
+// QueueDepth returns the number of inputs waiting.
+func (s *scheduled[I]) QueueDepth() int {
+	return s.queue.len()
+}
+
+// Process handles all inputs until the queue is closed and drained.
+func (s *scheduled[I]) Process(p ProcessorFunc[I]) {
+	process(s.ProcessContext, p)
+}
+
+// ProcessContext handles all inputs until the queue is closed and drained, or the ctx is done.  See
+// FanOut.ProcessContext for cancellation and error handling.
+func (s *scheduled[I]) ProcessContext(ctx context.Context, p ContextProcessorFunc[I]) error {
+	errs := &errorCollector[I]{onError: s.onError, onPanic: s.onPanic}
+
+	return processWorkers(ctx, s.queue.stop, errs, func(spawn func(func() bool)) {
+		for range s.workerCount {
+			spawn(func() bool {
+				for {
+					input, reason, ok := s.queue.pop(ctx)
+					if !ok {
+						return reason == exitCancelled
+					}
+
+					if err := safeProcess(ctx, p, input); err != nil {
+						errs.report(ctx, input, err)
+					}
+				}
+			})
+		}
+	})
+}
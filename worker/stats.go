@@ -0,0 +1,236 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Log field names used by the pool Log methods.
+const (
+	LogWorkerEnqueued    = "worker.enqueued"
+	LogWorkerProcessed   = "worker.processed"
+	LogWorkerFailed      = "worker.failed"
+	LogWorkerBusy        = "worker.busy"
+	LogWorkerIdle        = "worker.idle"
+	LogWorkerQueued      = "worker.queued"
+	LogWorkerDurationP50 = "worker.duration_p50"
+	LogWorkerDurationP99 = "worker.duration_p99"
+	LogWorkerDurationMax = "worker.duration_max"
+	LogWorkerLoad        = "worker.load"
+)
+
+// DurationBuckets are the upper bounds of the processing duration histograms, read when a pool is created.
+var DurationBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is a snapshot of processing durations.  Counts has one entry per bound, plus a final entry for durations
+// greater than the last bound.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Count  uint64
+	Sum    time.Duration
+	Max    time.Duration
+}
+
+// Mean returns the average duration, 0 if empty.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the upper bound of the bucket containing the q quantile (0-1), or Max if beyond the last bound.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(h.Count))
+	seen := uint64(0)
+
+	for i, c := range h.Counts {
+		seen += c
+		if seen > rank && i < len(h.Bounds) {
+			return min(h.Bounds[i], h.Max)
+		}
+	}
+
+	return h.Max
+}
+
+func (h *Histogram) add(o Histogram) {
+	if h.Counts == nil {
+		h.Bounds = o.Bounds
+		h.Counts = make([]uint64, len(o.Counts))
+	}
+
+	for i, c := range o.Counts {
+		h.Counts[i] += c
+	}
+
+	h.Count += o.Count
+	h.Sum += o.Sum
+	h.Max = max(h.Max, o.Max)
+}
+
+// WorkerStats is a point in time snapshot of a single HashedFanOut worker.
+type WorkerStats struct {
+	Processed uint64
+	Failed    uint64
+	Busy      bool
+	Queued    int
+	Duration  Histogram
+}
+
+// Stats is a point in time snapshot of pool usage.
+type Stats struct {
+	Enqueued  uint64
+	Processed uint64
+	Failed    uint64
+	Busy      int
+	Idle      int
+	// Queued is the length of each input channel, FanOut has a single shared channel.
+	Queued   []int
+	Duration Histogram
+	// Workers is per worker stats, HashedFanOut only.
+	Workers []WorkerStats
+}
+
+// QueuedTotal returns the total inputs waiting across all channels.
+func (s Stats) QueuedTotal() int {
+	total := 0
+	for _, q := range s.Queued {
+		total += q
+	}
+
+	return total
+}
+
+func (s Stats) log(l zerolog.Logger) {
+	e := l.Info().
+		Uint64(LogWorkerEnqueued, s.Enqueued).
+		Uint64(LogWorkerProcessed, s.Processed).
+		Uint64(LogWorkerFailed, s.Failed).
+		Int(LogWorkerBusy, s.Busy).
+		Int(LogWorkerIdle, s.Idle).
+		Ints(LogWorkerQueued, s.Queued).
+		Dur(LogWorkerDurationP50, s.Duration.Quantile(0.5)).
+		Dur(LogWorkerDurationP99, s.Duration.Quantile(0.99)).
+		Dur(LogWorkerDurationMax, s.Duration.Max)
+
+	if len(s.Workers) > 0 {
+		load := make([]uint64, len(s.Workers))
+		for i, w := range s.Workers {
+			load[i] = w.Processed
+		}
+
+		e = e.Uints64(LogWorkerLoad, load)
+	}
+
+	e.Msg("worker stats")
+}
+
+// logEvery calls log every `interval` until the ctx is done.
+func logEvery(ctx context.Context, interval time.Duration, log func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			log()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// histogram records durations with atomic counters.
+type histogram struct {
+	bounds []time.Duration
+	counts []atomic.Uint64
+	sum    atomic.Int64
+	max    atomic.Int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{bounds: DurationBuckets, counts: make([]atomic.Uint64, len(DurationBuckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+
+	for {
+		m := h.max.Load()
+		if int64(d) <= m || h.max.CompareAndSwap(m, int64(d)) {
+			return
+		}
+	}
+}
+
+func (h *histogram) snapshot() Histogram {
+	out := Histogram{
+		Bounds: h.bounds,
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    time.Duration(h.sum.Load()),
+		Max:    time.Duration(h.max.Load()),
+	}
+
+	for i := range h.counts {
+		out.Counts[i] = h.counts[i].Load()
+		out.Count += out.Counts[i]
+	}
+
+	return out
+}
+
+// counters tracks processing for one or more workers.
+type counters struct {
+	processed atomic.Uint64
+	failed    atomic.Uint64
+	running   atomic.Int64
+	busy      atomic.Int64
+	duration  *histogram
+}
+
+func newCounters() *counters {
+	return &counters{duration: newHistogram()}
+}
+
+func (c *counters) start() time.Time {
+	c.busy.Add(1)
+
+	return time.Now()
+}
+
+func (c *counters) done(start time.Time, err error) {
+	c.duration.observe(time.Since(start))
+	c.processed.Add(1)
+
+	if err != nil {
+		c.failed.Add(1)
+	}
+
+	c.busy.Add(-1)
+}
@@ -0,0 +1,149 @@
+package worker_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bir/iken/worker"
+)
+
+func TestHistogram(t *testing.T) {
+	h := worker.Histogram{
+		Bounds: []time.Duration{time.Millisecond, 10 * time.Millisecond},
+		Counts: []uint64{50, 49, 1},
+		Count:  100,
+		Sum:    time.Second,
+		Max:    time.Minute,
+	}
+
+	assert.Equal(t, 10*time.Millisecond, h.Mean())
+	assert.Equal(t, time.Millisecond, h.Quantile(0.25))
+	assert.Equal(t, 10*time.Millisecond, h.Quantile(0.5))
+	assert.Equal(t, time.Minute, h.Quantile(0.99))
+	assert.Equal(t, time.Duration(0), worker.Histogram{}.Mean())
+	assert.Equal(t, time.Duration(0), worker.Histogram{}.Quantile(0.5))
+
+	h.Max = 500 * time.Microsecond
+	assert.Equal(t, 500*time.Microsecond, h.Quantile(0.25), "capped by max")
+}
+
+func TestFanOutStats(t *testing.T) {
+	w := worker.NewFanOut[int](2, 10)
+	for _, i := range testInts(5) {
+		require.NoError(t, w.Invoke(i))
+	}
+
+	s := w.Stats()
+	assert.Equal(t, uint64(5), s.Enqueued)
+	assert.Equal(t, []int{5}, s.Queued)
+	assert.Equal(t, 5, s.QueuedTotal())
+	assert.Equal(t, 0, s.Busy+s.Idle, "not processing")
+
+	release := make(chan struct{})
+	busy := make(chan struct{})
+	done := make(chan error)
+
+	go func() {
+		done <- w.ProcessContext(context.Background(), func(_ context.Context, i int) error {
+			if i == 1 {
+				close(busy)
+				<-release
+			}
+
+			if i%2 == 0 {
+				return errOdd
+			}
+
+			return nil
+		})
+	}()
+
+	<-busy
+
+	assert.Eventually(t, func() bool {
+		s = w.Stats()
+
+		return s.Busy == 1 && s.Idle == 1 && s.QueuedTotal() == 0
+	}, time.Second, time.Millisecond, "one worker blocked, the other drained the queue")
+
+	close(release)
+	w.Close()
+	require.ErrorIs(t, <-done, errOdd)
+
+	s = w.Stats()
+	assert.Equal(t, uint64(5), s.Processed)
+	assert.Equal(t, uint64(2), s.Failed)
+	assert.Equal(t, uint64(5), s.Duration.Count)
+	assert.Nil(t, s.Workers)
+	assert.Equal(t, 0, s.Busy+s.Idle)
+}
+
+func TestHashedFanOutStats(t *testing.T) {
+	// Hot key, everything hashes to worker 1.
+	w := worker.NewHashedFanOut[int](3, 10, func(int) uint { return 1 })
+	for _, i := range testInts(4) {
+		require.NoError(t, w.Invoke(i))
+	}
+
+	s := w.Stats()
+	assert.Equal(t, []int{0, 4, 0}, s.Queued)
+	require.Len(t, s.Workers, 3)
+	assert.Equal(t, 4, s.Workers[1].Queued)
+
+	w.Close()
+	require.NoError(t, w.ProcessContext(context.Background(), func(context.Context, int) error { return nil }))
+
+	s = w.Stats()
+	assert.Equal(t, uint64(4), s.Enqueued)
+	assert.Equal(t, uint64(4), s.Processed)
+	assert.Equal(t, uint64(0), s.Workers[0].Processed)
+	assert.Equal(t, uint64(4), s.Workers[1].Processed)
+	assert.Equal(t, uint64(4), s.Workers[1].Duration.Count)
+	assert.Equal(t, uint64(4), s.Duration.Count)
+
+	var buf bytes.Buffer
+
+	w.Log(zerolog.New(&buf))
+
+	out := buf.String()
+	assert.True(t, strings.Contains(out, `"worker.processed":4`), out)
+	assert.True(t, strings.Contains(out, `"worker.load":[0,4,0]`), out)
+	assert.True(t, strings.Contains(out, `"message":"worker stats"`), out)
+}
+
+type syncBuffer struct {
+	ch chan string
+}
+
+func (b syncBuffer) Write(p []byte) (int, error) {
+	b.ch <- string(p)
+
+	return len(p), nil
+}
+
+func TestFanOutLogEvery(t *testing.T) {
+	out := syncBuffer{ch: make(chan string, 10)}
+	w := worker.NewFanOut[int](1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		w.LogEvery(ctx, zerolog.New(out), time.Millisecond)
+	}()
+
+	line := <-out.ch
+	assert.True(t, strings.Contains(line, `"worker.enqueued":0`), line)
+
+	cancel()
+	<-done
+}